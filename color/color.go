@@ -1,6 +1,9 @@
 package color
 
-import "fmt"
+import (
+	"fmt"
+	"hash/fnv"
+)
 
 // Red wraps the input string in ansii red color escape codes.
 func Red(input string) string {
@@ -21,3 +24,23 @@ func Yellow(input string) string {
 func Blue(input string) string {
 	return fmt.Sprintf("\x1b[34m%s\x1b[0m", input)
 }
+
+// Magenta wraps the input string in ansii magenta color escape codes.
+func Magenta(input string) string {
+	return fmt.Sprintf("\x1b[35m%s\x1b[0m", input)
+}
+
+// Cyan wraps the input string in ansii cyan color escape codes.
+func Cyan(input string) string {
+	return fmt.Sprintf("\x1b[36m%s\x1b[0m", input)
+}
+
+// Stable wraps the input string in a color chosen by key, so the same
+// key is always shown in the same color. Red is left out so it can be
+// kept for errors.
+func Stable(key, input string) string {
+	colors := []func(string) string{Green, Yellow, Blue, Magenta, Cyan}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return colors[h.Sum32()%uint32(len(colors))](input)
+}
@@ -1,12 +1,18 @@
 package cmd
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
 
-	"github.com/pkar/runit"
+	"github.com/upsight/ron/target"
+	"github.com/upsight/ron/watch"
 )
 
 // Command ...
@@ -27,26 +33,148 @@ func (c *Command) Run(args []string) (int, error) {
 	f := flag.NewFlagSet(c.Name, flag.ExitOnError)
 	f.SetOutput(c.WErr)
 	f.Usage = func() {
-		fmt.Fprintf(c.W, "Usage: %s %s -watch <path> -wait -restart <command> -ignore <patterns>\n", c.AppName, c.Name)
+		fmt.Fprintf(c.W, "Usage: %s %s -watch <path> -wait -restart -include <globs> -ignore <globs> <command>\n       %s %s -procfile <path> | -processes [process...]\n", c.AppName, c.Name, c.AppName, c.Name)
 		f.PrintDefaults()
 	}
 
-	alive := f.Bool("restart", false, "Restart the command if it dies.")
-	watch := f.String("watch", "", "Path to directory or file to watch.")
+	alive := f.Bool("restart", false, "Restart the command if it dies, backing off while it keeps failing.")
+	watchPath := f.String("watch", "", "Path to directory or file to watch.")
 	wait := f.Bool("wait", false, "With watch wait for file changes before running the command.")
-	ignore := f.String("ignore", `.*\.git.*,.*\.DS_Store$,.*\.pyc$`, "a comma seperated list of regex patterns to ignore *optional")
+	include := f.String("include", "", "With watch a comma separated list of globs of files which restart the command, all files by default.")
+	ignore := f.String("ignore", ".DS_Store,*.pyc,*.swp,*~", "With watch a comma separated list of globs of files and directories to ignore.")
+	gitignore := f.Bool("gitignore", true, "With watch ignore the files in the watched directory's .gitignore.")
+	debounce := f.Duration("debounce", watch.DefaultDebounce, "With watch how long to wait for changes to settle before restarting.")
+	quiet := f.Bool("quiet", false, "Don't print messages when the command is started or restarted.")
+	procfile := f.String("procfile", "", "Run each process in a Procfile of name: command lines, or only the processes given as arguments.")
+	processes := f.Bool("processes", false, "Run each process in the processes section of the ron.yaml config, or only the processes given as arguments.")
 	f.Parse(args)
-	if f.NArg() < 1 {
+	supervised := *procfile != "" || *processes
+	if f.NArg() < 1 && !supervised {
 		f.Usage()
 		return 1, nil
 	}
 
-	ignoreList := strings.Split(*ignore, ",")
-	runner, err := runit.New(strings.Join(f.Args(), " "), *watch, ignoreList, *alive, *wait)
+	stdOut, stdErr := c.W, c.WErr
+	if stdOut == nil {
+		stdOut = os.Stdout
+	}
+	if stdErr == nil {
+		stdErr = os.Stderr
+	}
+	// newRunner creates a runner for cmd which watches files in the
+	// current directory matching globs when given, otherwise the -watch path.
+	newRunner := func(cmd string, globs []string) (*runner, error) {
+		r := &runner{cmd: cmd, wait: *wait, stdOut: stdOut, stdErr: stdErr, log: stdErr}
+		if *alive {
+			r.restart = restartAlways
+		}
+		if *quiet {
+			r.log = nil
+		}
+		path := *watchPath
+		if len(globs) > 0 {
+			path = "."
+		} else {
+			globs = splitList(*include)
+		}
+		if path == "" {
+			return r, nil
+		}
+		w, err := newWatcher(path, *gitignore)
+		if err != nil {
+			return nil, err
+		}
+		w.Include = append(w.Include, globs...)
+		w.Exclude = splitList(*ignore)
+		w.Debounce = *debounce
+		r.watcher = w
+		return r, nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if supervised {
+		all, err := loadProcesses(*procfile, stdOut, stdErr)
+		if err != nil {
+			return 1, err
+		}
+		selected, err := selectProcesses(all, f.Args())
+		if err != nil {
+			return 1, err
+		}
+		return supervise(ctx, selected, stdOut, stdErr, *quiet, func(p *target.Process) (*runner, error) {
+			r, err := newRunner(p.Cmd, p.Watch)
+			if err != nil {
+				return nil, err
+			}
+			if r.restart == restartNever {
+				r.restart = restartOnFailure
+			}
+			return r, nil
+		})
+	}
+	r, err := newRunner(strings.Join(f.Args(), " "), nil)
 	if err != nil {
 		return 1, err
 	}
-	return runner.Do()
+	return r.run(ctx)
+}
+
+// loadProcesses reads the processes from the procfile, or the processes
+// section of the found configs when empty. When a config is found in a
+// parent directory the working directory is changed to it.
+func loadProcesses(procfile string, stdOut, stdErr io.Writer) ([]*target.Process, error) {
+	if procfile != "" {
+		return readProcfile(procfile)
+	}
+	configs, foundConfigDir, err := target.LoadConfigFiles("", "", true)
+	if err != nil {
+		return nil, err
+	}
+	if foundConfigDir != "" {
+		os.Chdir(foundConfigDir)
+	}
+	targetConfig, err := target.NewConfigs(configs, "", stdOut, stdErr)
+	if err != nil {
+		return nil, err
+	}
+	return targetConfig.Processes(), nil
+}
+
+// newWatcher watches the directory path, or if path is a file the
+// directory containing it for changes to only that file.
+func newWatcher(path string, gitignore bool) (*watch.Watcher, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	root := path
+	if !info.IsDir() {
+		root = filepath.Dir(path)
+	}
+	w, err := watch.New(root)
+	if err != nil {
+		return nil, err
+	}
+	if !gitignore {
+		w.Ignore = &watch.Ignore{}
+		w.Ignore.Add(".git/")
+	}
+	if !info.IsDir() {
+		w.Include = []string{"/" + filepath.Base(path)}
+	}
+	return w, nil
+}
+
+// splitList splits a comma separated list, dropping empty values.
+func splitList(s string) []string {
+	list := []string{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
 }
 
 // Aliases are the aliases and name for the command. For instance
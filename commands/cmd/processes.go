@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/upsight/ron/execute"
+	"github.com/upsight/ron/target"
+)
+
+// selectProcesses returns the named processes, or all of them when no
+// names are given.
+func selectProcesses(processes []*target.Process, names []string) ([]*target.Process, error) {
+	if len(names) == 0 {
+		if len(processes) == 0 {
+			return nil, fmt.Errorf("no processes found")
+		}
+		return processes, nil
+	}
+	byName := map[string]*target.Process{}
+	for _, p := range processes {
+		byName[p.Name] = p
+	}
+	selected := []*target.Process{}
+	for _, name := range names {
+		p, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("%s process not found", name)
+		}
+		selected = append(selected, p)
+	}
+	return selected, nil
+}
+
+// supervise runs a runner for each process until ctx is done, prefixing
+// their output with the process name. If a runner fails the others are
+// stopped and its error returned.
+func supervise(ctx context.Context, processes []*target.Process, stdOut, stdErr io.Writer, quiet bool, newRunner func(p *target.Process) (*runner, error)) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	outMux := execute.NewMux(stdOut, execute.OutputPrefixed)
+	errMux := execute.NewMux(stdErr, execute.OutputPrefixed)
+
+	runners := []*runner{}
+	for _, p := range processes {
+		r, err := newRunner(p)
+		if err != nil {
+			return 1, fmt.Errorf("%s: %v", p.Name, err)
+		}
+		if p.File != nil {
+			envs, err := p.File.Env.Config()
+			if err != nil {
+				return 1, fmt.Errorf("%s: %v", p.Name, err)
+			}
+			r.envs = envs
+		}
+		out, errOut := outMux.Writer(p.Name), errMux.Writer(p.Name)
+		defer out.Close()
+		defer errOut.Close()
+		r.stdOut, r.stdErr = out, errOut
+		r.log = errOut
+		if quiet {
+			r.log = nil
+		}
+		runners = append(runners, r)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for i, r := range runners {
+		wg.Add(1)
+		go func(name string, r *runner) {
+			defer wg.Done()
+			status, err := r.run(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if err == nil {
+				r.logf("exited %d", status)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %v", name, err)
+				cancel()
+			}
+		}(processes[i].Name, r)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return 1, firstErr
+	}
+	return 0, nil
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/upsight/ron/target"
+)
+
+func TestParseProcfile(t *testing.T) {
+	procfile := `
+# dev processes
+web: go run ./cmd/web -port=$PORT
+worker:   ./worker --queue default
+`
+	processes, err := parseProcfile("Procfile", strings.NewReader(procfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(processes) != 2 {
+		t.Fatalf("expected 2 processes got %d", len(processes))
+	}
+	if processes[0].Name != "web" || processes[0].Cmd != "go run ./cmd/web -port=$PORT" {
+		t.Errorf("unexpected web process %+v", processes[0])
+	}
+	if processes[1].Name != "worker" || processes[1].Cmd != "./worker --queue default" {
+		t.Errorf("unexpected worker process %+v", processes[1])
+	}
+}
+
+func TestParseProcfileErr(t *testing.T) {
+	for _, procfile := range []string{
+		"web",
+		": ls",
+		"web:",
+		"web: ls\nweb: ls",
+	} {
+		if _, err := parseProcfile("Procfile", strings.NewReader(procfile)); err == nil {
+			t.Errorf("expected error for %q", procfile)
+		}
+	}
+}
+
+func TestReadProcfileErr(t *testing.T) {
+	if _, err := readProcfile("nothere"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestSelectProcesses(t *testing.T) {
+	all := []*target.Process{{Name: "a"}, {Name: "b"}}
+	got, err := selectProcesses(all, nil)
+	if err != nil || len(got) != 2 {
+		t.Fatalf("expected all processes got %v %v", got, err)
+	}
+	got, err = selectProcesses(all, []string{"b"})
+	if err != nil || len(got) != 1 || got[0].Name != "b" {
+		t.Fatalf("expected b got %v %v", got, err)
+	}
+	if _, err := selectProcesses(all, []string{"c"}); err == nil {
+		t.Error("expected not found error")
+	}
+	if _, err := selectProcesses(nil, nil); err == nil {
+		t.Error("expected no processes error")
+	}
+}
+
+func TestSupervise(t *testing.T) {
+	prev := minBackoff
+	defer func() { minBackoff = prev }()
+	minBackoff = 10 * time.Millisecond
+
+	stdOut, stdErr := &syncBuffer{}, &syncBuffer{}
+	processes := []*target.Process{
+		{Name: "web", Cmd: "echo serving; sleep 30"},
+		{Name: "worker", Cmd: "echo working; exit 1"},
+		{Name: "once", Cmd: "echo done"},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan int)
+	go func() {
+		status, _ := supervise(ctx, processes, stdOut, stdErr, false, func(p *target.Process) (*runner, error) {
+			return &runner{cmd: p.Cmd, restart: restartOnFailure}, nil
+		})
+		done <- status
+	}()
+	waitFor(t, stdOut, "working\n", 3)
+	waitFor(t, stdOut, "serving\n", 1)
+	cancel()
+	select {
+	case status := <-done:
+		if status != 0 {
+			t.Errorf("expected 0 got %d", status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("processes were not stopped")
+	}
+	if strings.Count(stdOut.String(), "done\n") != 1 {
+		t.Errorf("expected once to not be restarted got %q", stdOut.String())
+	}
+	output := regexp.MustCompile("\x1b\\[[0-9;]*m").ReplaceAllString(stdOut.String()+stdErr.String(), "")
+	for _, want := range []string{"web] serving", "worker] working", "worker] exited 1, restarting", "once] exited 0"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output got %q", want, output)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/upsight/ron/target"
+)
+
+// readProcfile reads a Procfile of "name: command" lines, skipping blank
+// lines and comments.
+func readProcfile(path string) ([]*target.Process, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseProcfile(path, f)
+}
+
+func parseProcfile(path string, r io.Reader) ([]*target.Process, error) {
+	processes := []*target.Process{}
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.Index(line, ":")
+		if i < 1 {
+			return nil, fmt.Errorf("%s:%d: expected name: command", path, n)
+		}
+		name, cmd := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		if cmd == "" {
+			return nil, fmt.Errorf("%s:%d: %s has no command", path, n, name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%s:%d: duplicate process %s", path, n, name)
+		}
+		seen[name] = true
+		processes = append(processes, &target.Process{Name: name, Cmd: cmd})
+	}
+	return processes, scanner.Err()
+}
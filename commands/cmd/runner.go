@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/upsight/ron/color"
+	"github.com/upsight/ron/execute"
+	"github.com/upsight/ron/watch"
+)
+
+var (
+	// minBackoff and maxBackoff bound the delay before restarting a
+	// command which keeps failing.
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+	// stableAfter is how long a command must run for a failure to not
+	// count towards a crash loop.
+	stableAfter = 10 * time.Second
+)
+
+// restart policies of a runner.
+const (
+	restartNever = iota
+	restartAlways
+	restartOnFailure
+)
+
+// runner runs a shell command, optionally restarting it when it exits and
+// when watched files change.
+type runner struct {
+	cmd     string
+	envs    map[string]string
+	restart int
+	wait    bool
+	watcher *watch.Watcher
+	stdOut  io.Writer
+	stdErr  io.Writer
+	// log receives messages about starting and stopping the command, nil
+	// is quiet.
+	log io.Writer
+}
+
+// result is how a run of the command ended.
+type result struct {
+	status  int
+	err     error
+	elapsed time.Duration
+}
+
+// logf writes a message to the runners log.
+func (r *runner) logf(format string, a ...interface{}) {
+	if r.log == nil {
+		return
+	}
+	fmt.Fprintln(r.log, color.Yellow(fmt.Sprintf(format, a...)))
+}
+
+// start runs the command in the background, sending how it ended on the
+// returned channel. Cancelling ctx terminates its process group.
+func (r *runner) start(ctx context.Context) <-chan result {
+	done := make(chan result, 1)
+	r.logf("running %s", r.cmd)
+	go func() {
+		started := time.Now()
+		status, err := execute.CommandContext(ctx, r.cmd, r.stdOut, r.stdErr, r.envs)
+		done <- result{status: status, err: err, elapsed: time.Since(started)}
+	}()
+	return done
+}
+
+// run runs the command until ctx is done. When the command exits and isn't
+// restarted by the restart policy and there is no watcher it returns the
+// commands exit status. With a watcher, changed files stop the command if
+// it is still running and run it again, with wait set the first run waits
+// for a change. Restarts back off while the command keeps failing quickly.
+func (r *runner) run(ctx context.Context) (int, error) {
+	changes := make(chan []string)
+	watchErr := make(chan error, 1)
+	if r.watcher != nil {
+		go func() {
+			watchErr <- r.watcher.Watch(ctx, func(files []string) {
+				select {
+				case changes <- files:
+				case <-ctx.Done():
+				}
+			})
+		}()
+	}
+
+	var (
+		cancel  context.CancelFunc = func() {}
+		done    <-chan result
+		retry   <-chan time.Time
+		backoff time.Duration
+	)
+	stop := func() {
+		cancel()
+		if done != nil {
+			<-done
+			done = nil
+		}
+	}
+	run := func() {
+		var runCtx context.Context
+		runCtx, cancel = context.WithCancel(ctx)
+		done = r.start(runCtx)
+	}
+	if !r.wait || r.watcher == nil {
+		run()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			stop()
+			return 0, nil
+		case err := <-watchErr:
+			stop()
+			if err == context.Canceled {
+				return 0, nil
+			}
+			return 1, err
+		case files := <-changes:
+			r.logf("changed %s, restarting", watch.Describe(files))
+			stop()
+			retry, backoff = nil, 0
+			run()
+		case <-retry:
+			retry = nil
+			run()
+		case res := <-done:
+			done = nil
+			cancel()
+			failed := res.status != 0 || res.err != nil
+			restart := r.restart == restartAlways || r.restart == restartOnFailure && failed
+			switch {
+			case !restart && r.watcher == nil:
+				return res.status, res.err
+			case !restart:
+				r.logf("exited %d, waiting for changes", res.status)
+			default:
+				backoff = nextBackoff(backoff, res)
+				r.logf("exited %d, restarting in %s", res.status, backoff)
+				retry = time.After(backoff)
+			}
+		}
+	}
+}
+
+// nextBackoff doubles the delay while the command keeps failing soon after
+// starting, otherwise the minimum delay is used.
+func nextBackoff(backoff time.Duration, res result) time.Duration {
+	if res.status == 0 && res.err == nil || res.elapsed >= stableAfter || backoff == 0 {
+		return minBackoff
+	}
+	backoff *= 2
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+// waitFor waits until the buffer contains n occurrences of s.
+func waitFor(t *testing.T, b *syncBuffer, s string, n int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for strings.Count(b.String(), s) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d %q got %q", n, s, b.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNextBackoff(t *testing.T) {
+	fail := result{status: 1, err: errors.New("exit status 1"), elapsed: time.Millisecond}
+	tests := []struct {
+		name    string
+		backoff time.Duration
+		res     result
+		want    time.Duration
+	}{
+		{"first failure", 0, fail, minBackoff},
+		{"crash loop", 2 * time.Second, fail, 4 * time.Second},
+		{"max", maxBackoff, fail, maxBackoff},
+		{"success", 8 * time.Second, result{elapsed: time.Millisecond}, minBackoff},
+		{"stable", 8 * time.Second, result{status: 1, elapsed: stableAfter}, minBackoff},
+	}
+	for _, tt := range tests {
+		if got := nextBackoff(tt.backoff, tt.res); got != tt.want {
+			t.Errorf("%s want %s got %s", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestRunnerRestart(t *testing.T) {
+	prev := minBackoff
+	defer func() { minBackoff = prev }()
+	minBackoff = 10 * time.Millisecond
+
+	stdOut, log := &syncBuffer{}, &syncBuffer{}
+	r := &runner{cmd: "echo hi; exit 3", restart: restartAlways, stdOut: stdOut, stdErr: stdOut, log: log}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan int)
+	go func() {
+		status, _ := r.run(ctx)
+		done <- status
+	}()
+	waitFor(t, stdOut, "hi\n", 3)
+	cancel()
+	if status := <-done; status != 0 {
+		t.Errorf("expected 0 got %d", status)
+	}
+	if !strings.Contains(log.String(), "exited 3, restarting in 20ms") {
+		t.Errorf("expected backoff in log got %q", log.String())
+	}
+}
+
+func TestRunnerWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "roncmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "a.txt")
+	if err := ioutil.WriteFile(file, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := newWatcher(file, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Debounce = 20 * time.Millisecond
+
+	stdOut := &syncBuffer{}
+	r := &runner{cmd: "echo start; sleep 30", wait: true, watcher: w, stdOut: stdOut, stdErr: stdOut}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan int)
+	go func() {
+		status, _ := r.run(ctx)
+		done <- status
+	}()
+	// with wait nothing runs until a change and only a.txt is watched.
+	time.Sleep(100 * time.Millisecond)
+	ioutil.WriteFile(filepath.Join(dir, "b.txt"), []byte("b"), 0644)
+	os.Chmod(file, 0600)
+	time.Sleep(100 * time.Millisecond)
+	if stdOut.String() != "" {
+		t.Fatalf("expected no run got %q", stdOut.String())
+	}
+	ioutil.WriteFile(file, []byte("b"), 0644)
+	waitFor(t, stdOut, "start\n", 1)
+	// a change while running stops the previous run.
+	ioutil.WriteFile(file, []byte("c"), 0644)
+	waitFor(t, stdOut, "start\n", 2)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("runner did not stop")
+	}
+}
+
+func TestNewWatcherErr(t *testing.T) {
+	if _, err := newWatcher("nothere", true); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	got := splitList(" *.go, ,vendor,")
+	if strings.Join(got, "|") != "*.go|vendor" {
+		t.Errorf("expected *.go|vendor got %v", got)
+	}
+}
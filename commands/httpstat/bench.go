@@ -0,0 +1,263 @@
+package httpstat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Bench is the distribution of phase timings over repeated requests.
+type Bench struct {
+	URL         string         `json:"url"`
+	Requests    int            `json:"requests"`
+	Concurrency int            `json:"concurrency"`
+	Reused      int            `json:"reused"`
+	Statuses    map[int]int    `json:"statuses"`
+	Errors      map[string]int `json:"errors"`
+	Phases      []*PhaseStats  `json:"phases"`
+}
+
+// PhaseStats are the statistics in milliseconds of a phase over the
+// requests which went through it. Reused connections skip DNS, TCP and
+// TLS so Count can be lower than the successful requests.
+type PhaseStats struct {
+	Phase string  `json:"phase"`
+	Count int     `json:"count"`
+	Min   float64 `json:"min"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+	Max   float64 `json:"max"`
+}
+
+// phases are the benchmarked phases and how to get them from Timings.
+var phases = []struct {
+	name string
+	get  func(t Timings) time.Duration
+}{
+	{"DNS Lookup", func(t Timings) time.Duration { return t.DNSLookup }},
+	{"TCP Connection", func(t Timings) time.Duration { return t.TCPConnection }},
+	{"Proxy CONNECT", func(t Timings) time.Duration { return t.ProxyConnect }},
+	{"TLS Handshake", func(t Timings) time.Duration { return t.TLSHandshake }},
+	{"Server Processing", func(t Timings) time.Duration { return t.ServerProcessing }},
+	{"Content Transfer", func(t Timings) time.Duration { return t.ContentTransfer }},
+	{"Total", func(t Timings) time.Duration { return t.Total }},
+}
+
+// bodyError is an error reading a response body.
+type bodyError struct {
+	err error
+}
+
+func (e *bodyError) Error() string {
+	return fmt.Sprintf("failed to read response body: %v", e.err)
+}
+
+func (e *bodyError) Unwrap() error {
+	return e.err
+}
+
+// bench requests url n times from concurrency goroutines sharing one
+// client. Redirects are not followed.
+func (c *Command) bench(url *url.URL, n, concurrency int) (*Bench, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > n {
+		concurrency = n
+	}
+	req, err := c.newRequest(c.method, url, c.body)
+	if err != nil {
+		return nil, err
+	}
+	client, err := c.newClient(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results []*Result
+		errs    []error
+	)
+	jobs := make(chan struct{})
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				req, err := c.newRequest(c.method, url, c.body)
+				var result *Result
+				if err == nil {
+					result, err = c.roundTrip(client, req)
+				}
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, err)
+				} else {
+					results = append(results, result)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- struct{}{}
+	}
+	close(jobs)
+	wg.Wait()
+
+	b := &Bench{
+		URL:         url.String(),
+		Requests:    n,
+		Concurrency: concurrency,
+		Statuses:    map[int]int{},
+		Errors:      map[string]int{},
+	}
+	for _, err := range errs {
+		b.Errors[errorKind(err)]++
+	}
+	for _, r := range results {
+		b.Statuses[r.Status]++
+		if r.Reused {
+			b.Reused++
+		}
+	}
+	for _, p := range phases {
+		durations := []time.Duration{}
+		for _, r := range results {
+			if d := p.get(r.Timings); d > 0 || p.name == "Total" {
+				durations = append(durations, d)
+			}
+		}
+		if stats := phaseStats(p.name, durations); stats != nil {
+			b.Phases = append(b.Phases, stats)
+		}
+	}
+	return b, nil
+}
+
+// phaseStats returns the statistics of the durations, nil if there are
+// none.
+func phaseStats(name string, durations []time.Duration) *PhaseStats {
+	if len(durations) == 0 {
+		return nil
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	var sum time.Duration
+	for _, d := range durations {
+		sum += d
+	}
+	return &PhaseStats{
+		Phase: name,
+		Count: len(durations),
+		Min:   milliseconds(durations[0]),
+		Mean:  milliseconds(sum / time.Duration(len(durations))),
+		P50:   milliseconds(percentile(durations, 50)),
+		P90:   milliseconds(percentile(durations, 90)),
+		P99:   milliseconds(percentile(durations, 99)),
+		Max:   milliseconds(durations[len(durations)-1]),
+	}
+}
+
+// percentile returns the nearest rank percentile p of the sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// milliseconds converts d to fractional milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// errorKind groups request errors by the phase they happened in.
+func errorKind(err error) string {
+	var (
+		netErr  net.Error
+		dnsErr  *net.DNSError
+		opErr   *net.OpError
+		bodyErr *bodyError
+	)
+	switch {
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.As(err, &dnsErr):
+		return "dns"
+	case strings.Contains(err.Error(), "tls:") || strings.Contains(err.Error(), "x509:"):
+		return "tls"
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return "connect"
+	case errors.As(err, &bodyErr):
+		return "body"
+	}
+	return "other"
+}
+
+// writeText writes a table of the phase statistics followed by the status
+// and error counts.
+func (b *Bench) writeText(w io.Writer) {
+	failed := 0
+	for _, n := range b.Errors {
+		failed += n
+	}
+	fmt.Fprintf(w, "\n%d requests to %s with concurrency %d, %d reused connections, %d errors\n\n",
+		b.Requests, b.URL, b.Concurrency, b.Reused, failed)
+
+	if len(b.Phases) > 0 {
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "PHASE\tCOUNT\tMIN\tMEAN\tP50\tP90\tP99\tMAX")
+		for _, p := range b.Phases {
+			fmt.Fprintf(tw, "%s\t%d\t%.2fms\t%.2fms\t%.2fms\t%.2fms\t%.2fms\t%.2fms\n",
+				p.Phase, p.Count, p.Min, p.Mean, p.P50, p.P90, p.P99, p.Max)
+		}
+		tw.Flush()
+		fmt.Fprintln(w)
+	}
+
+	if len(b.Statuses) > 0 {
+		codes := make([]int, 0, len(b.Statuses))
+		for code := range b.Statuses {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		counts := make([]string, 0, len(codes))
+		for _, code := range codes {
+			counts = append(counts, fmt.Sprintf("%d x%d", code, b.Statuses[code]))
+		}
+		fmt.Fprintf(w, "Status codes: %s\n", strings.Join(counts, ", "))
+	}
+	if failed > 0 {
+		kinds := make([]string, 0, len(b.Errors))
+		for kind := range b.Errors {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		counts := make([]string, 0, len(kinds))
+		for _, kind := range kinds {
+			counts = append(counts, fmt.Sprintf("%s x%d", kind, b.Errors[kind]))
+		}
+		fmt.Fprintf(w, "Errors: %s\n", strings.Join(counts, ", "))
+	}
+}
+
+// writeJSON writes the benchmark as an indented JSON document.
+func (b *Bench) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
@@ -0,0 +1,78 @@
+package httpstat
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPhaseStats(t *testing.T) {
+	durations := []time.Duration{}
+	for i := 100; i > 0; i-- {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+	got := phaseStats("Total", durations)
+	want := &PhaseStats{Phase: "Total", Count: 100, Min: 1, Mean: 50.5, P50: 50, P90: 90, P99: 99, Max: 100}
+	if *got != *want {
+		t.Errorf("want %+v got %+v", want, got)
+	}
+	if phaseStats("Total", nil) != nil {
+		t.Error("expected no stats")
+	}
+}
+
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&url.Error{Op: "Get", Err: &net.DNSError{Err: "no such host"}}, "dns"},
+		{&url.Error{Op: "Get", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}, "connect"},
+		{&url.Error{Op: "Get", Err: &net.DNSError{IsTimeout: true}}, "timeout"},
+		{&url.Error{Op: "Get", Err: errors.New("x509: certificate signed by unknown authority")}, "tls"},
+		{&bodyError{errors.New("unexpected EOF")}, "body"},
+		{errors.New("boom"), "other"},
+	}
+	for _, tt := range tests {
+		if got := errorKind(tt.err); got != tt.want {
+			t.Errorf("%v want %s got %s", tt.err, tt.want, got)
+		}
+	}
+}
+
+func TestBench(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests%5 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	c := &Command{method: http.MethodGet, reuse: true}
+	u, _ := url.Parse(ts.URL)
+	b, err := c.bench(u, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Requests != 10 || b.Statuses[200] != 8 || b.Statuses[503] != 2 || len(b.Errors) != 0 {
+		t.Errorf("unexpected bench %+v", b)
+	}
+	if b.Reused != 9 {
+		t.Errorf("expected 9 reused connections got %d", b.Reused)
+	}
+	buf := &bytes.Buffer{}
+	b.writeText(buf)
+	for _, s := range []string{"10 requests", "TCP Connection     1 ", "Total              10 ", "Status codes: 200 x8, 503 x2"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("expected %q in %s", s, buf.String())
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package httpstat
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// readResponseBody consumes the body of the response, decoding it with
+// --compressed, keeping it when it is shown or checked and saving it with
+// -o. The result gets the body's sizes along with an informational message
+// about the disposition of the response body's contents.
+func (c *Command) readResponseBody(req *http.Request, resp *http.Response, result *Result) error {
+	if c.isRedirect(resp.StatusCode) || req.Method == http.MethodHead {
+		return nil
+	}
+
+	raw := &countingReader{r: resp.Body}
+	var r io.Reader = raw
+	encoding := strings.ToLower(resp.Header.Get("Content-Encoding"))
+	if c.compressed && encoding != "" && encoding != "identity" {
+		var err error
+		switch encoding {
+		case "gzip":
+			r, err = gzip.NewReader(raw)
+		case "deflate":
+			r, err = zlib.NewReader(raw)
+		default:
+			err = fmt.Errorf("unsupported content encoding %s", encoding)
+		}
+		if err != nil {
+			return &bodyError{err}
+		}
+		result.Encoding = encoding
+	}
+
+	var (
+		w    io.Writer = ioutil.Discard
+		body *bytes.Buffer
+	)
+	if c.showBody || c.expect != nil && c.expect.body != nil {
+		body = &bytes.Buffer{}
+		w = body
+	}
+	if c.bodyFile != "" {
+		f, err := os.Create(c.bodyFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = io.MultiWriter(w, f)
+	}
+	n, err := io.Copy(w, r)
+	if err != nil {
+		return &bodyError{err}
+	}
+	result.Size = n
+	if result.Encoding != "" {
+		result.CompressedSize = raw.n
+	}
+
+	if body != nil {
+		result.body = body.String()
+	}
+	switch {
+	case c.showBody:
+		result.Body = result.body
+		result.bodyMsg = result.body
+	case c.bodyFile != "":
+		result.bodyMsg = "Body saved to " + c.bodyFile
+	default:
+		result.bodyMsg = "Body discarded"
+	}
+	return nil
+}
+
+// sizeText describes the size of the body and how it was compressed.
+func (r *Result) sizeText() string {
+	if r.Encoding == "" {
+		return fmt.Sprintf("%d bytes", r.Size)
+	}
+	return fmt.Sprintf("%d bytes, %d bytes %s compressed", r.Size, r.CompressedSize, r.Encoding)
+}
@@ -0,0 +1,164 @@
+package httpstat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// ignoredHeaders always differ between responses so aren't compared.
+var ignoredHeaders = map[string]bool{
+	"Date": true,
+}
+
+// Comparison is the final response of two urls requested Runs times each
+// with the median of each phase as their timings.
+type Comparison struct {
+	A    *Result `json:"a"`
+	B    *Result `json:"b"`
+	Runs int     `json:"runs"`
+	// Delta is B's timings minus A's.
+	Delta Timings `json:"delta"`
+	// Headers are the headers with different values, as A's then B's.
+	Headers map[string][2]string `json:"headers,omitempty"`
+}
+
+// compare visits a and b alternately n times, following redirects.
+func (c *Command) compare(a, b *url.URL, n int) (*Comparison, error) {
+	if n < 1 {
+		n = 1
+	}
+	var last [2]*Result
+	timings := [2][]Timings{}
+	for i := 0; i < n; i++ {
+		for j, u := range []*url.URL{a, b} {
+			results, err := c.visit(u, c.method, c.body)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", u, err)
+			}
+			last[j] = results[len(results)-1]
+			timings[j] = append(timings[j], last[j].Timings)
+		}
+	}
+	for j := range last {
+		r := *last[j]
+		r.Timings = medianTimings(timings[j])
+		last[j] = &r
+	}
+
+	cmp := &Comparison{A: last[0], B: last[1], Runs: n, Headers: map[string][2]string{}}
+	deltas, as, bs := cmp.Delta.fields(), cmp.A.Timings.fields(), cmp.B.Timings.fields()
+	for k, d := range deltas {
+		*d = *bs[k] - *as[k]
+	}
+	for _, k := range headerNames(cmp.A, cmp.B) {
+		va, vb := strings.Join(cmp.A.Header[k], ","), strings.Join(cmp.B.Header[k], ",")
+		if va != vb && !ignoredHeaders[k] {
+			cmp.Headers[k] = [2]string{va, vb}
+		}
+	}
+	return cmp, nil
+}
+
+// medianTimings returns the median of each phase.
+func medianTimings(timings []Timings) Timings {
+	median := Timings{}
+	for k, d := range median.fields() {
+		durations := make([]time.Duration, 0, len(timings))
+		for i := range timings {
+			durations = append(durations, *timings[i].fields()[k])
+		}
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		*d = percentile(durations, 50)
+	}
+	return median
+}
+
+// headerNames returns the sorted header names of both results.
+func headerNames(results ...*Result) []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, r := range results {
+		for k := range r.Header {
+			if !seen[k] {
+				seen[k] = true
+				names = append(names, k)
+			}
+		}
+	}
+	sort.Sort(headers(names))
+	return names
+}
+
+// writeText writes the responses and phases side by side with the delta
+// of each phase.
+func (cmp *Comparison) writeText(w io.Writer) {
+	if cmp.Runs > 1 {
+		fmt.Fprintf(w, "\nMedian of %d runs each\n", cmp.Runs)
+	}
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "\tA\tB\tDELTA\n")
+	fmt.Fprintf(tw, "URL\t%s\t%s\t\n", cmp.A.URL, cmp.B.URL)
+	fmt.Fprintf(tw, "Remote Address\t%s\t%s\t\n", cmp.A.RemoteAddr, cmp.B.RemoteAddr)
+	fmt.Fprintf(tw, "Status\t%d\t%d\t%s\n", cmp.A.Status, cmp.B.Status, differs(cmp.A.Status != cmp.B.Status))
+	fmt.Fprintf(tw, "Protocol\t%s\t%s\t%s\n", cmp.A.Proto, cmp.B.Proto, differs(cmp.A.Proto != cmp.B.Proto))
+	for _, p := range phases {
+		a, b := p.get(cmp.A.Timings), p.get(cmp.B.Timings)
+		if a == 0 && b == 0 && p.name != "Total" {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%.2fms\t%.2fms\t%s\n", p.name, milliseconds(a), milliseconds(b), delta(a, b))
+	}
+	tw.Flush()
+
+	if len(cmp.Headers) == 0 {
+		return
+	}
+	names := make([]string, 0, len(cmp.Headers))
+	for k := range cmp.Headers {
+		names = append(names, k)
+	}
+	sort.Sort(headers(names))
+	fmt.Fprintf(w, "\nDiffering headers\n")
+	tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, k := range names {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", k, orMissing(cmp.Headers[k][0]), orMissing(cmp.Headers[k][1]))
+	}
+	tw.Flush()
+}
+
+// writeJSON writes the comparison as an indented JSON document.
+func (cmp *Comparison) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cmp)
+}
+
+// delta formats the change from a to b.
+func delta(a, b time.Duration) string {
+	s := fmt.Sprintf("%+.2fms", milliseconds(b-a))
+	if a > 0 {
+		s += fmt.Sprintf(" (%+.0f%%)", float64(b-a)/float64(a)*100)
+	}
+	return s
+}
+
+func differs(d bool) string {
+	if d {
+		return "differs"
+	}
+	return ""
+}
+
+func orMissing(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,97 @@
+package httpstat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunCompare(t *testing.T) {
+	requests := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		switch r.URL.Path {
+		case "/prod":
+			w.Header().Set("Server", "nginx")
+			w.Header().Set("X-Cache", "MISS")
+		case "/canary":
+			w.Header().Set("Server", "envoy")
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer ts.Close()
+
+	c, stdOut, _ := newCommand()
+	status, err := c.Run([]string{"--compare", "-n", "3", ts.URL + "/prod", ts.URL + "/canary"})
+	if status != 0 || err != nil {
+		t.Fatalf("expected 0 got %d %v", status, err)
+	}
+	if requests["/prod"] != 3 || requests["/canary"] != 3 {
+		t.Errorf("expected 3 requests each got %v", requests)
+	}
+	out := stdOut.String()
+	for _, s := range []string{"Median of 3 runs each", "Status             200", "202", "differs", "Total", "Differing headers", "Server   nginx  envoy", "X-Cache  MISS   -"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected %q in %s", s, out)
+		}
+	}
+	if strings.Contains(out, "Date ") {
+		t.Errorf("expected Date to be ignored in %s", out)
+	}
+
+	c, _, _ = newCommand()
+	if status, _ := c.Run([]string{"--compare", ts.URL}); status != 1 {
+		t.Errorf("expected usage got %d", status)
+	}
+}
+
+func TestRunCompareCredentials(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string]string{}
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, password, _ := r.BasicAuth()
+			mu.Lock()
+			seen[name] = user + ":" + password + " " + r.Header.Get("Cookie")
+			mu.Unlock()
+		})
+	}
+	prod := httptest.NewServer(handler("prod"))
+	defer prod.Close()
+	canary := httptest.NewServer(handler("canary"))
+	defer canary.Close()
+
+	c, _, _ := newCommand()
+	status, err := c.Run([]string{"--compare", "-u", "me:secret", "-b", "session=abc", prod.URL, canary.URL})
+	if status != 0 || err != nil {
+		t.Fatalf("expected 0 got %d %v", status, err)
+	}
+	for _, name := range []string{"prod", "canary"} {
+		if seen[name] != "me:secret session=abc" {
+			t.Errorf("expected credentials and cookies sent to %s got %q", name, seen[name])
+		}
+	}
+}
+
+func TestMedianTimings(t *testing.T) {
+	got := medianTimings([]Timings{
+		{Total: 3 * time.Millisecond, DNSLookup: time.Millisecond},
+		{Total: time.Millisecond},
+		{Total: 2 * time.Millisecond, DNSLookup: time.Millisecond},
+	})
+	if got.Total != 2*time.Millisecond || got.DNSLookup != time.Millisecond {
+		t.Errorf("unexpected median %+v", got)
+	}
+}
+
+func TestDelta(t *testing.T) {
+	if got := delta(10*time.Millisecond, 15*time.Millisecond); got != "+5.00ms (+50%)" {
+		t.Errorf("unexpected delta %s", got)
+	}
+	if got := delta(0, time.Millisecond); got != "+1.00ms" {
+		t.Errorf("unexpected delta %s", got)
+	}
+}
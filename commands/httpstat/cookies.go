@@ -0,0 +1,245 @@
+package httpstat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// concurrencyFlag is the -c flag, which is the concurrency of -n when it
+// is a number otherwise like curl the file to write the cookies to.
+type concurrencyFlag struct {
+	concurrency *int
+	file        *string
+}
+
+// Set implements flag.Value
+func (c *concurrencyFlag) Set(v string) error {
+	if n, err := strconv.Atoi(v); err == nil {
+		*c.concurrency = n
+		return nil
+	}
+	*c.file = v
+	return nil
+}
+
+// String implements flag.Value
+func (c *concurrencyFlag) String() string {
+	if c.concurrency == nil {
+		return ""
+	}
+	return strconv.Itoa(*c.concurrency)
+}
+
+// cookie is a line of a Netscape cookie file as read by -b and written
+// by -c.
+type cookie struct {
+	domain     string
+	subdomains bool
+	path       string
+	secure     bool
+	httpOnly   bool
+	expires    time.Time
+	name       string
+	value      string
+}
+
+// cookieJar is a cookie jar which keeps the cookies it was given so they
+// can be written to a cookie file.
+type cookieJar struct {
+	jar     *cookiejar.Jar
+	mu      sync.Mutex
+	cookies []*cookie
+}
+
+func newCookieJar() *cookieJar {
+	jar, _ := cookiejar.New(nil)
+	return &cookieJar{jar: jar}
+}
+
+// SetCookies implements http.CookieJar
+func (j *cookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+	now := time.Now()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, hc := range cookies {
+		c := &cookie{
+			domain:     strings.TrimPrefix(hc.Domain, "."),
+			subdomains: hc.Domain != "",
+			path:       hc.Path,
+			secure:     hc.Secure,
+			httpOnly:   hc.HttpOnly,
+			expires:    hc.Expires,
+			name:       hc.Name,
+			value:      hc.Value,
+		}
+		if c.domain == "" {
+			c.domain = u.Hostname()
+		}
+		if c.path == "" {
+			c.path = "/"
+		}
+		if hc.MaxAge > 0 {
+			c.expires = now.Add(time.Duration(hc.MaxAge) * time.Second)
+		}
+		deleted := hc.MaxAge < 0 || !c.expires.IsZero() && c.expires.Before(now)
+		j.remove(c)
+		if !deleted {
+			j.cookies = append(j.cookies, c)
+		}
+	}
+}
+
+// remove removes a cookie with the same domain, path and name.
+func (j *cookieJar) remove(c *cookie) {
+	kept := j.cookies[:0]
+	for _, old := range j.cookies {
+		if old.domain != c.domain || old.path != c.path || old.name != c.name {
+			kept = append(kept, old)
+		}
+	}
+	j.cookies = kept
+}
+
+// Cookies implements http.CookieJar
+func (j *cookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// load adds the cookies of -b, either name=value pairs sent to u or a
+// Netscape cookie file.
+func (j *cookieJar) load(u *url.URL, b string) error {
+	if strings.Contains(b, "=") {
+		cookies := []*http.Cookie{}
+		for _, pair := range strings.Split(b, ";") {
+			i := strings.Index(pair, "=")
+			if i == -1 {
+				continue
+			}
+			cookies = append(cookies, &http.Cookie{
+				Name:  strings.TrimSpace(pair[:i]),
+				Value: strings.TrimSpace(pair[i+1:]),
+			})
+		}
+		j.SetCookies(u, cookies)
+		return nil
+	}
+	f, err := os.Open(b)
+	if err != nil {
+		return fmt.Errorf("failed to read cookies: %v", err)
+	}
+	defer f.Close()
+	cookies, err := readCookies(f)
+	if err != nil {
+		return fmt.Errorf("failed to read cookies from %s: %v", b, err)
+	}
+	for _, c := range cookies {
+		scheme := "http"
+		if c.secure {
+			scheme = "https"
+		}
+		hc := &http.Cookie{
+			Name:     c.name,
+			Value:    c.value,
+			Path:     c.path,
+			Secure:   c.secure,
+			HttpOnly: c.httpOnly,
+			Expires:  c.expires,
+		}
+		if c.subdomains {
+			hc.Domain = c.domain
+		}
+		j.SetCookies(&url.URL{Scheme: scheme, Host: c.domain, Path: c.path}, []*http.Cookie{hc})
+	}
+	return nil
+}
+
+// readCookies parses a Netscape cookie file.
+func readCookies(r io.Reader) ([]*cookie, error) {
+	cookies := []*cookie{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		httpOnly := strings.HasPrefix(text, "#HttpOnly_")
+		if httpOnly {
+			text = strings.TrimPrefix(text, "#HttpOnly_")
+		}
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("line %d has %d fields, expected 7", line, len(fields))
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d has invalid expiry %q", line, fields[4])
+		}
+		c := &cookie{
+			domain:     strings.TrimPrefix(fields[0], "."),
+			subdomains: fields[1] == "TRUE",
+			path:       fields[2],
+			secure:     fields[3] == "TRUE",
+			httpOnly:   httpOnly,
+			name:       fields[5],
+			value:      fields[6],
+		}
+		if expires > 0 {
+			c.expires = time.Unix(expires, 0)
+		}
+		cookies = append(cookies, c)
+	}
+	return cookies, scanner.Err()
+}
+
+// write writes the cookies as a Netscape cookie file.
+func (j *cookieJar) write(w io.Writer) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# Netscape HTTP Cookie File")
+	tf := func(b bool) string {
+		if b {
+			return "TRUE"
+		}
+		return "FALSE"
+	}
+	for _, c := range j.cookies {
+		domain := c.domain
+		if c.subdomains {
+			domain = "." + domain
+		}
+		if c.httpOnly {
+			domain = "#HttpOnly_" + domain
+		}
+		var expires int64
+		if !c.expires.IsZero() {
+			expires = c.expires.Unix()
+		}
+		fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, tf(c.subdomains), c.path, tf(c.secure), expires, c.name, c.value)
+	}
+	return bw.Flush()
+}
+
+// writeFile writes the cookie file for -c.
+func (j *cookieJar) writeFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := j.write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
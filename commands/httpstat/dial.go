@@ -0,0 +1,148 @@
+package httpstat
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// resolves are curl style host:port:addr[,addr] overrides of DNS.
+type resolves map[string][]string
+
+// Set implements flag.Value
+func (r *resolves) Set(v string) error {
+	parts := splitHostPorts(v)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return fmt.Errorf("%q must be host:port:addr", v)
+	}
+	if *r == nil {
+		*r = resolves{}
+	}
+	key := net.JoinHostPort(strings.ToLower(parts[0]), parts[1])
+	for _, addr := range strings.Split(parts[2], ",") {
+		addr = strings.Trim(addr, "[]")
+		if net.ParseIP(addr) == nil {
+			return fmt.Errorf("%q is not an IP address", addr)
+		}
+		(*r)[key] = append((*r)[key], net.JoinHostPort(addr, parts[1]))
+	}
+	return nil
+}
+
+// String implements flag.Value
+func (r resolves) String() string {
+	var o []string
+	for k, addrs := range r {
+		o = append(o, "--resolve "+k+":"+strings.Join(addrs, ","))
+	}
+	return strings.Join(o, " ")
+}
+
+// connectTo is a curl style HOST1:PORT1:HOST2:PORT2 rule, empty parts
+// match any host or port and keep the original host or port.
+type connectTo struct {
+	fromHost, fromPort, toHost, toPort string
+}
+
+// connectTos are the rules of --connect-to, the first matching rule is
+// used.
+type connectTos []connectTo
+
+// Set implements flag.Value
+func (c *connectTos) Set(v string) error {
+	parts := splitHostPorts(v)
+	if len(parts) != 4 {
+		return fmt.Errorf("%q must be HOST1:PORT1:HOST2:PORT2", v)
+	}
+	*c = append(*c, connectTo{
+		fromHost: strings.ToLower(strings.Trim(parts[0], "[]")),
+		fromPort: parts[1],
+		toHost:   strings.Trim(parts[2], "[]"),
+		toPort:   parts[3],
+	})
+	return nil
+}
+
+// String implements flag.Value
+func (c connectTos) String() string {
+	var o []string
+	for _, r := range c {
+		o = append(o, fmt.Sprintf("--connect-to %s:%s:%s:%s", r.fromHost, r.fromPort, r.toHost, r.toPort))
+	}
+	return strings.Join(o, " ")
+}
+
+// target returns the address to connect to instead of addr.
+func (c connectTos) target(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	for _, r := range c {
+		if r.fromHost != "" && r.fromHost != strings.ToLower(host) || r.fromPort != "" && r.fromPort != port {
+			continue
+		}
+		if r.toHost != "" {
+			host = r.toHost
+		}
+		if r.toPort != "" {
+			port = r.toPort
+		}
+		return net.JoinHostPort(host, port)
+	}
+	return addr
+}
+
+// splitHostPorts splits s on the colons which aren't inside the brackets
+// of an IPv6 address.
+func splitHostPorts(s string) []string {
+	parts := []string{}
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ':':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// dialContext dials the --unix-socket or the address chosen by
+// --connect-to and --resolve with the address family of -4 or -6.
+func (c *Command) dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		switch {
+		case c.unixSocket != "":
+			return dialer.DialContext(ctx, "unix", c.unixSocket)
+		case c.ipv4:
+			network = "tcp4"
+		case c.ipv6:
+			network = "tcp6"
+		}
+		addr = c.connectTo.target(addr)
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		addrs, ok := c.resolve[net.JoinHostPort(strings.ToLower(host), port)]
+		if !ok {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		for _, a := range addrs {
+			var conn net.Conn
+			conn, err = dialer.DialContext(ctx, network, a)
+			if err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
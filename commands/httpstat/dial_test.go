@@ -0,0 +1,87 @@
+package httpstat
+
+import (
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResolvesSet(t *testing.T) {
+	r := resolves{}
+	for _, v := range []string{"Example.com:443:127.0.0.1,[::1]", "a.com:80:10.0.0.1"} {
+		if err := r.Set(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := strings.Join(r["example.com:443"], " "); got != "127.0.0.1:443 [::1]:443" {
+		t.Errorf("unexpected addrs %s", got)
+	}
+	if got := strings.Join(r["a.com:80"], " "); got != "10.0.0.1:80" {
+		t.Errorf("unexpected addrs %s", got)
+	}
+	for _, v := range []string{"example.com:443", "example.com::127.0.0.1", "example.com:443:nothere"} {
+		if err := r.Set(v); err == nil {
+			t.Errorf("%s expected error", v)
+		}
+	}
+}
+
+func TestConnectTosTarget(t *testing.T) {
+	c := connectTos{}
+	for _, v := range []string{"example.com:443:backend:8443", "::[::1]:", "a.com:80::81"} {
+		if err := c.Set(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"example.com:443", "backend:8443"},
+		{"a.com:80", "[::1]:80"},
+		{"EXAMPLE.com:80", "[::1]:80"},
+	}
+	for _, tt := range tests {
+		if got := c.target(tt.addr); got != tt.want {
+			t.Errorf("%s want %s got %s", tt.addr, tt.want, got)
+		}
+	}
+	if got := (connectTos{{fromHost: "a.com", toPort: "81"}}).target("a.com:80"); got != "a.com:81" {
+		t.Errorf("expected a.com:81 got %s", got)
+	}
+	if err := c.Set("a:b:c"); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestRunResolve(t *testing.T) {
+	ts := httptest.NewServer(newTestHandler())
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+
+	tests := [][]string{
+		{"-resolve", "example.test:" + port + ":127.0.0.1", "http://example.test:" + port},
+		{"-connect-to", "example.test:80:127.0.0.1:" + port, "http://example.test:80"},
+		{"-4", "-connect-to", "::localhost:" + port, "http://example.test:80"},
+	}
+	for _, args := range tests {
+		c, stdOut, _ := newCommand()
+		status, err := c.Run(args)
+		if status != 0 || err != nil {
+			t.Fatalf("%v expected 0 got %d %v", args, status, err)
+		}
+		if !strings.Contains(stdOut.String(), "Connected to 127.0.0.1:"+port) {
+			t.Errorf("%v unexpected output %s", args, stdOut.String())
+		}
+	}
+
+	c, _, _ := newCommand()
+	if status, err := c.Run([]string{"-6", ts.URL}); status != 1 || err == nil {
+		t.Errorf("expected -6 to fail connecting to an IPv4 address got %d %v", status, err)
+	}
+	c, _, _ = newCommand()
+	if status, err := c.Run([]string{"-4", "-6", ts.URL}); status != 1 || err == nil {
+		t.Errorf("expected error got %d %v", status, err)
+	}
+}
@@ -0,0 +1,121 @@
+package httpstat
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// expectations are the assertions checked against the final response.
+type expectations struct {
+	status  []int
+	headers []expectedHeader
+	body    *regexp.Regexp
+	budgets []budget
+}
+
+// expectedHeader is a header which must be set and contain value.
+type expectedHeader struct {
+	name  string
+	value string
+}
+
+// budget is the longest a phase may take.
+type budget struct {
+	phase string
+	max   time.Duration
+	get   func(t Timings) time.Duration
+}
+
+// expectations parses the assertion flags.
+func (c *Command) expectations() (*expectations, error) {
+	e := &expectations{}
+	if c.expectStatus != "" {
+		for _, s := range strings.Split(c.expectStatus, ",") {
+			status, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				return nil, fmt.Errorf("invalid --expect-status %q", c.expectStatus)
+			}
+			e.status = append(e.status, status)
+		}
+	}
+	for _, h := range c.expectHeaders {
+		name, value := h, ""
+		if strings.Contains(h, ":") {
+			var err error
+			name, value, err = c.headerKeyValue(h)
+			if err != nil {
+				return nil, err
+			}
+		}
+		e.headers = append(e.headers, expectedHeader{name, value})
+	}
+	if c.expectBody != "" {
+		re, err := regexp.Compile(c.expectBody)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --expect-body-regex: %v", err)
+		}
+		e.body = re
+	}
+	for _, b := range []budget{
+		{"dns", c.maxDNS, func(t Timings) time.Duration { return t.DNSLookup }},
+		{"connect", c.maxConnect, func(t Timings) time.Duration { return t.TCPConnection }},
+		{"tls", c.maxTLS, func(t Timings) time.Duration { return t.TLSHandshake }},
+		{"server", c.maxServer, func(t Timings) time.Duration { return t.ServerProcessing }},
+		{"transfer", c.maxTransfer, func(t Timings) time.Duration { return t.ContentTransfer }},
+		{"total", c.maxTotal, func(t Timings) time.Duration { return t.Total }},
+	} {
+		if b.max > 0 {
+			e.budgets = append(e.budgets, b)
+		}
+	}
+	return e, nil
+}
+
+// empty reports whether there is nothing to check.
+func (e *expectations) empty() bool {
+	return len(e.status) == 0 && len(e.headers) == 0 && e.body == nil && len(e.budgets) == 0
+}
+
+// check returns a short message for each failed assertion.
+func (e *expectations) check(r *Result) []string {
+	failures := []string{}
+	if len(e.status) > 0 {
+		ok := false
+		for _, s := range e.status {
+			ok = ok || r.Status == s
+		}
+		if !ok {
+			failures = append(failures, fmt.Sprintf("status %d, expected %s", r.Status, joinInts(e.status, " or ")))
+		}
+	}
+	for _, h := range e.headers {
+		values, ok := r.Header[http.CanonicalHeaderKey(h.name)]
+		switch {
+		case !ok:
+			failures = append(failures, fmt.Sprintf("header %s missing", h.name))
+		case h.value != "" && !strings.Contains(strings.Join(values, ","), h.value):
+			failures = append(failures, fmt.Sprintf("header %s is %q, expected %q", h.name, strings.Join(values, ","), h.value))
+		}
+	}
+	if e.body != nil && !e.body.MatchString(r.body) {
+		failures = append(failures, fmt.Sprintf("body doesn't match %s", e.body))
+	}
+	for _, b := range e.budgets {
+		if d := b.get(r.Timings); d > b.max {
+			failures = append(failures, fmt.Sprintf("%s took %s, over %s", b.phase, d.Round(100*time.Microsecond), b.max))
+		}
+	}
+	return failures
+}
+
+func joinInts(ints []int, sep string) string {
+	s := make([]string, 0, len(ints))
+	for _, i := range ints {
+		s = append(s, strconv.Itoa(i))
+	}
+	return strings.Join(s, sep)
+}
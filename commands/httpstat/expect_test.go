@@ -0,0 +1,63 @@
+package httpstat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunExpect(t *testing.T) {
+	ts := httptest.NewServer(newTestHandler())
+	defer ts.Close()
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--expect-status", "200", "--expect-header", "Content-Type: text/plain", "--expect-body-regex", "^hello", "--max-total", "5s"}, ""},
+		{[]string{"--expect-status", "201, 204"}, "status 200, expected 201 or 204"},
+		{[]string{"--expect-header", "Cache-Control"}, "header Cache-Control missing"},
+		{[]string{"--expect-header", "content-type: json"}, `header content-type is "text/plain; charset=utf-8", expected "json"`},
+		{[]string{"--expect-body-regex", "bye", "--max-server", "1ns"}, "body doesn't match bye; server took "},
+		{[]string{"--expect-status", "ok"}, "invalid --expect-status"},
+		{[]string{"--expect-body-regex", "("}, "invalid --expect-body-regex"},
+		{[]string{"-n", "2", "--max-total", "1s"}, "assertions can't be used with -n"},
+	}
+	for _, tt := range tests {
+		c, stdOut, _ := newCommand()
+		status, err := c.Run(append(tt.args, ts.URL+"/redirect/"))
+		if tt.want == "" {
+			if status != 0 || err != nil {
+				t.Errorf("%v expected 0 got %d %v", tt.args, status, err)
+			}
+			if strings.Contains(stdOut.String(), "hello") {
+				t.Errorf("%v expected the body to not be shown", tt.args)
+			}
+			continue
+		}
+		if status != 1 || err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v expected %q got %d %v", tt.args, tt.want, status, err)
+		}
+	}
+}
+
+func TestExpectationsCheck(t *testing.T) {
+	c := &Command{maxTotal: 500 * time.Millisecond, maxDNS: 10 * time.Millisecond}
+	e, err := c.expectations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Result{Status: 200, Header: http.Header{}, Timings: Timings{DNSLookup: 5 * time.Millisecond, Total: 612 * time.Millisecond}}
+	got := strings.Join(e.check(r), "; ")
+	if got != "total took 612ms, over 500ms" {
+		t.Errorf("unexpected failures %q", got)
+	}
+	if e.empty() {
+		t.Error("expected budgets")
+	}
+	if e, _ := (&Command{}).expectations(); !e.empty() {
+		t.Error("expected no assertions")
+	}
+}
@@ -8,15 +8,11 @@ import (
 	"flag"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"log"
 	"net"
 	"net/http"
 	"net/http/httptrace"
 	"net/url"
 	"os"
-	"sort"
-	"strconv"
 	"strings"
 	"time"
 
@@ -48,15 +44,49 @@ const (
 
 // Command ...
 type Command struct {
-	Name              string
-	W                 io.Writer
-	WErr              io.Writer
-	AppName           string
-	method            string
-	body              string
-	headers           headers
-	redirectsFollowed int
-	showBody          bool
+	Name          string
+	W             io.Writer
+	WErr          io.Writer
+	AppName       string
+	method        string
+	body          string
+	headers       headers
+	showBody      bool
+	output        string
+	requests      int
+	concurrency   int
+	reuse         bool
+	compareURLs   bool
+	insecure      bool
+	caCert        string
+	cert          string
+	key           string
+	resolve       resolves
+	connectTo     connectTos
+	ipv4          bool
+	ipv6          bool
+	unixSocket    string
+	proxy         string
+	expectStatus  string
+	expectHeaders headers
+	expectBody    string
+	maxDNS        time.Duration
+	maxConnect    time.Duration
+	maxTLS        time.Duration
+	maxServer     time.Duration
+	maxTransfer   time.Duration
+	maxTotal      time.Duration
+	bodyFile      string
+	compressed    bool
+	user          string
+	authHosts     map[string]bool
+	cookies       string
+	cookieFile    string
+	jar           *cookieJar
+	http1         bool
+	http2         bool
+	follow        bool
+	expect        *expectations
 }
 
 // Key returns the commands name for sorting.
@@ -66,218 +96,366 @@ func (c *Command) Key() string {
 
 // Run ...
 func (c *Command) Run(args []string) (int, error) {
-	f := flag.NewFlagSet(c.Name, flag.ExitOnError)
+	f := flag.NewFlagSet(c.Name, flag.ContinueOnError)
+	f.SetOutput(c.WErr)
 	f.Usage = func() {
 		fmt.Fprintf(c.W, "Usage: %s %s [OPTIONS] URL\n", c.AppName, c.Name)
+		fmt.Fprintf(c.W, "       %s %s [OPTIONS] --compare URL1 URL2\n", c.AppName, c.Name)
 		f.PrintDefaults()
 	}
-	f.StringVar(&c.method, "X", "GET", "HTTP method to use")
-	f.StringVar(&c.body, "d", "", "the body for POST or PUT requests")
+	f.StringVar(&c.method, "X", "", "HTTP method to use, GET or POST with -d by default")
+	f.StringVar(&c.body, "d", "", "the request body, or @file to read it from a file")
 	f.BoolVar(&c.showBody, "v", false, "show the body for the response")
 	f.Var(&c.headers, "H", "set HTTP headers -H 'Accept: ...' -H 'Range: ...'")
-	f.Parse(args)
-	if len(f.Args()) != 1 {
+	f.StringVar(&c.output, "format", OutputText, "output format, text or json")
+	f.Var(&outputFlag{&c.output, &c.bodyFile}, "o", "file to save the response body to, or the output format when text or json")
+	f.BoolVar(&c.compressed, "compressed", false, "request a gzip or deflate compressed response and report both sizes")
+	f.StringVar(&c.user, "u", "", "user:password for basic authentication")
+	f.StringVar(&c.cookies, "b", "", "cookies to send, name=value pairs separated by ; or a Netscape cookie file")
+	f.StringVar(&c.cookieFile, "cookie-jar", "", "file to write the cookies to after the requests")
+	f.BoolVar(&c.http1, "http1.1", false, "only use HTTP/1.1")
+	f.BoolVar(&c.http2, "http2", false, "only use HTTP/2, which requires https")
+	f.BoolVar(&c.follow, "L", true, "follow redirects")
+	f.IntVar(&c.requests, "n", 1, "number of requests, more than 1 reports the distribution of each phase or with --compare the median")
+	f.BoolVar(&c.compareURLs, "compare", false, "request two urls and show their phases side by side")
+	f.IntVar(&c.concurrency, "concurrency", 1, "with -n the number of requests to make at a time")
+	f.Var(&concurrencyFlag{&c.concurrency, &c.cookieFile}, "c", "with -n the number of requests to make at a time, otherwise the file to write the cookies to")
+	f.BoolVar(&c.reuse, "reuse", true, "with -n reuse connections between requests")
+	f.BoolVar(&c.insecure, "k", false, "skip verifying the server's certificate chain and host name")
+	f.StringVar(&c.caCert, "cacert", "", "PEM file of CA certificates to verify the server with instead of the system's")
+	f.StringVar(&c.cert, "cert", "", "PEM file of the client certificate for mutual TLS")
+	f.StringVar(&c.key, "key", "", "PEM file of the client certificate's private key, defaults to the -cert file")
+	f.Var(&c.resolve, "resolve", "connect to addr instead of resolving host:port --resolve host:port:addr[,addr]")
+	f.Var(&c.connectTo, "connect-to", "connect to host2:port2 for requests to host1:port1 --connect-to host1:port1:host2:port2, empty parts match any")
+	f.BoolVar(&c.ipv4, "4", false, "only connect to IPv4 addresses")
+	f.BoolVar(&c.ipv6, "6", false, "only connect to IPv6 addresses")
+	f.StringVar(&c.unixSocket, "unix-socket", "", "connect to the unix socket at this path instead of the url's host")
+	f.StringVar(&c.proxy, "proxy", "", "proxy url to use instead of the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables")
+	f.StringVar(&c.expectStatus, "expect-status", "", "fail unless the final status is one of these comma separated codes")
+	f.Var(&c.expectHeaders, "expect-header", "fail unless the final response has the header, containing the value if given --expect-header 'Cache-Control: max-age'")
+	f.StringVar(&c.expectBody, "expect-body-regex", "", "fail unless the final response body matches the regular expression")
+	f.DurationVar(&c.maxDNS, "max-dns", 0, "fail if the DNS lookup takes longer")
+	f.DurationVar(&c.maxConnect, "max-connect", 0, "fail if the TCP connection takes longer")
+	f.DurationVar(&c.maxTLS, "max-tls", 0, "fail if the TLS handshake takes longer")
+	f.DurationVar(&c.maxServer, "max-server", 0, "fail if server processing takes longer")
+	f.DurationVar(&c.maxTransfer, "max-transfer", 0, "fail if the content transfer takes longer")
+	f.DurationVar(&c.maxTotal, "max-total", 0, "fail if the final request takes longer")
+	c.headers, c.resolve, c.connectTo, c.expectHeaders = nil, nil, nil, nil
+	c.bodyFile, c.jar = "", nil
+	if err := f.Parse(args); err != nil {
+		// the flag set has already printed the error and usage.
+		return 1, nil
+	}
+	if c.compareURLs && len(f.Args()) != 2 || !c.compareURLs && len(f.Args()) != 1 {
 		f.Usage()
 		return 1, nil
 	}
+	if c.ipv4 && c.ipv6 {
+		return 1, fmt.Errorf("only one of -4 and -6 can be used")
+	}
+	if c.unixSocket != "" && c.proxy != "" {
+		return 1, fmt.Errorf("only one of --unix-socket and --proxy can be used")
+	}
+	if c.output != OutputText && c.output != OutputJSON {
+		return 1, fmt.Errorf("unknown output format %q, must be %s or %s", c.output, OutputText, OutputJSON)
+	}
+	if c.method == "" {
+		c.method = http.MethodGet
+		if c.body != "" {
+			c.method = http.MethodPost
+		}
+	}
+	c.method = strings.ToUpper(c.method)
 	if (c.method == "POST" || c.method == "PUT") && c.body == "" {
-		log.Fatal("must supply post body using -d when POST or PUT is used")
+		return 1, fmt.Errorf("must supply post body using -d when POST or PUT is used")
 	}
+	if c.http1 && c.http2 {
+		return 1, fmt.Errorf("only one of --http1.1 and --http2 can be used")
+	}
+	if c.bodyFile != "" && (c.requests > 1 || c.compareURLs) {
+		return 1, fmt.Errorf("-o file can't be used with -n or --compare")
+	}
+	if c.cookieFile != "" && (c.requests > 1 || c.compareURLs) {
+		return 1, fmt.Errorf("-c file can't be used with -n or --compare")
+	}
+	expect, err := c.expectations()
+	if err != nil {
+		return 1, err
+	}
+	if (c.requests > 1 || c.compareURLs) && !expect.empty() {
+		return 1, fmt.Errorf("assertions can't be used with -n or --compare")
+	}
+	c.expect = expect
 
-	url := c.parseURL(f.Arg(0))
-	c.visit(url)
+	// with --compare both urls are origins the credentials and cookies
+	// are sent to.
+	urls := []*url.URL{}
+	for _, arg := range f.Args() {
+		u, err := c.parseURL(arg)
+		if err != nil {
+			return 1, err
+		}
+		urls = append(urls, u)
+	}
+	url := urls[0]
+	c.authHosts = map[string]bool{}
+	for _, u := range urls {
+		c.authHosts[u.Host] = true
+	}
+	if c.cookies != "" || c.cookieFile != "" {
+		c.jar = newCookieJar()
+	}
+	if c.cookies != "" {
+		for _, u := range urls {
+			if err := c.jar.load(u, c.cookies); err != nil {
+				return 1, err
+			}
+		}
+	}
+	if c.compareURLs {
+		cmp, err := c.compare(url, urls[1], c.requests)
+		if err != nil {
+			return 1, err
+		}
+		if c.output == OutputJSON {
+			if err := cmp.writeJSON(c.W); err != nil {
+				return 1, err
+			}
+		} else {
+			cmp.writeText(c.W)
+		}
+		return 0, nil
+	}
+	if c.requests > 1 {
+		b, err := c.bench(url, c.requests, c.concurrency)
+		if err != nil {
+			return 1, err
+		}
+		if c.output == OutputJSON {
+			if err := b.writeJSON(c.W); err != nil {
+				return 1, err
+			}
+		} else {
+			b.writeText(c.W)
+		}
+		if len(b.Errors) > 0 {
+			return 1, nil
+		}
+		return 0, nil
+	}
 
+	results, err := c.visit(url, c.method, c.body)
+	if c.cookieFile != "" {
+		if cerr := c.jar.writeFile(c.cookieFile); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	if len(results) == 0 {
+		return 1, err
+	}
+	if c.output == OutputJSON {
+		if werr := writeJSON(c.W, results); werr != nil && err == nil {
+			err = werr
+		}
+	} else {
+		writeText(c.W, results)
+	}
+	if err != nil {
+		return 1, err
+	}
+	if failures := expect.check(results[len(results)-1]); len(failures) > 0 {
+		return 1, fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
 	return 0, nil
 }
 
 // visit visits a url and times the interaction.
-// If the response is a 30x, visit follows the redirect.
-func (c *Command) visit(url *url.URL) {
-	req := c.newRequest(c.method, url, c.body)
+// If the response is a 30x, visit follows the redirect and returns a
+// result for each hop. When a hop fails the results of the hops before it
+// are returned with the error.
+func (c *Command) visit(url *url.URL, method, body string) ([]*Result, error) {
+	results := []*Result{}
+	for {
+		req, err := c.newRequest(method, url, body)
+		if err != nil {
+			return results, err
+		}
+		client, err := c.newClient(req)
+		if err != nil {
+			return results, err
+		}
+		result, err := c.roundTrip(client, req)
+		if err != nil {
+			if errorKind(err) == "tls" && !c.insecure {
+				return results, fmt.Errorf("failed to read response: %v, use -k to skip certificate verification", err)
+			}
+			return results, fmt.Errorf("failed to read response: %v", err)
+		}
+		results = append(results, result)
 
-	var t0, t1, t2, t3, t4 time.Time
+		if !c.follow || !c.isRedirect(result.Status) || result.Location == "" {
+			// a 30x without a Location can't be followed.
+			return results, nil
+		}
+		if len(results) > maxRedirects {
+			return results, fmt.Errorf("maximum number of redirects (%d) followed", maxRedirects)
+		}
+		url, err = url.Parse(result.Location)
+		if err != nil {
+			return results, fmt.Errorf("unable to follow redirect: %v", err)
+		}
 
-	trace := &httptrace.ClientTrace{
-		DNSStart: func(_ httptrace.DNSStartInfo) { t0 = time.Now() },
-		DNSDone:  func(_ httptrace.DNSDoneInfo) { t1 = time.Now() },
-		ConnectStart: func(_, _ string) {
-			if t1.IsZero() {
-				// connecting to IP
-				t1 = time.Now()
-			}
-		},
-		ConnectDone: func(net, addr string, err error) {
-			if err != nil {
-				log.Fatalf("unable to connect to host %v: %v", addr, err)
+		// like browsers a POST is changed to a GET without a body when
+		// redirected other than by a 307 or 308.
+		if result.Status == http.StatusSeeOther || method == http.MethodPost && result.Status != http.StatusTemporaryRedirect && result.Status != http.StatusPermanentRedirect {
+			if method != http.MethodHead {
+				method = http.MethodGet
 			}
-			t2 = time.Now()
+			body = ""
+		}
+	}
+}
 
-			fmt.Printf("\nConnected to %s\n", addr)
-		},
-		GotConn:              func(_ httptrace.GotConnInfo) { t3 = time.Now() },
-		GotFirstResponseByte: func() { t4 = time.Now() },
-	}
-	req = req.WithContext(httptrace.WithClientTrace(context.Background(), trace))
-	tr := &http.Transport{
-		Proxy:                 http.ProxyFromEnvironment,
-		MaxIdleConns:          100,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-	}
-	switch url.Scheme {
+// newClient creates a client which doesn't follow redirects for the
+// scheme of req.
+func (c *Command) newClient(req *http.Request) (*http.Client, error) {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	proxy, err := c.proxyFunc()
+	if err != nil {
+		return nil, err
+	}
+	transport := &http.Transport{
+		Proxy:                  proxy,
+		OnProxyConnectResponse: onProxyConnectResponse,
+		DialContext:            c.dialContext(dialer),
+		MaxIdleConns:           100,
+		MaxIdleConnsPerHost:    100,
+		IdleConnTimeout:        90 * time.Second,
+		TLSHandshakeTimeout:    10 * time.Second,
+		ExpectContinueTimeout:  1 * time.Second,
+		DisableKeepAlives:      !c.reuse,
+		// compression is only asked for with --compressed, which decodes
+		// the body itself to report both sizes.
+		DisableCompression: true,
+	}
+	if c.http2 && req.URL.Scheme != "https" {
+		return nil, fmt.Errorf("--http2 requires https, %s isn't", req.URL)
+	}
+	switch req.URL.Scheme {
 	case "https":
 		host, _, err := net.SplitHostPort(req.Host)
 		if err != nil {
 			host = req.Host
 		}
 
-		tr.TLSClientConfig = &tls.Config{
-			ServerName:         host,
-			InsecureSkipVerify: true,
+		transport.TLSClientConfig, err = c.tlsConfig(host)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.http1 {
+			// a non nil TLSNextProto disables HTTP/2.
+			transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+			transport.TLSClientConfig.NextProtos = []string{"http/1.1"}
+			break
 		}
 
 		// Because we create a custom TLSClientConfig, we have to opt-in to HTTP/2.
 		// See https://github.com/golang/go/issues/14275
-		err = http2.ConfigureTransport(tr)
+		err = http2.ConfigureTransport(transport)
 		if err != nil {
-			log.Fatalf("failed to prepare transport for HTTP/2: %v", err)
+			return nil, fmt.Errorf("failed to prepare transport for HTTP/2: %v", err)
+		}
+		if c.http2 {
+			transport.TLSClientConfig.NextProtos = []string{"h2"}
 		}
 	}
 
 	client := &http.Client{
-		Transport: tr,
+		Transport: transport,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			// always refuse to follow redirects, visit does that
 			// manually if required.
 			return http.ErrUseLastResponse
 		},
 	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Fatalf("failed to read response: %v", err)
-	}
-
-	bodyMsg := c.readResponseBody(req, resp)
-	resp.Body.Close()
-
-	t5 := time.Now() // after read body
-	if t0.IsZero() {
-		// we skipped DNS
-		t0 = t1
-	}
-
-	// print status line and headers
-	fmt.Printf("\nHTTP/%d.%d %s\n", resp.ProtoMajor, resp.ProtoMinor, resp.Status)
-
-	names := make([]string, 0, len(resp.Header))
-	for k := range resp.Header {
-		names = append(names, k)
-	}
-	sort.Sort(headers(names))
-	for _, k := range names {
-		fmt.Printf("%s: %s\n", k, strings.Join(resp.Header[k], ","))
-	}
-
-	if bodyMsg != "" {
-		fmt.Printf("\n%s\n", bodyMsg)
-	}
-
-	fmta := func(d time.Duration) string {
-		return fmt.Sprintf("%7dms", int(d/time.Millisecond))
+	if c.jar != nil {
+		client.Jar = c.jar
 	}
+	return client, nil
+}
 
-	fmtb := func(d time.Duration) string {
-		return fmt.Sprintf("%-9sms", strconv.Itoa(int(d/time.Millisecond)))
+// roundTrip sends a single request and times each phase of it.
+func (c *Command) roundTrip(client *http.Client, req *http.Request) (*Result, error) {
+	tr := &trace{}
+	var (
+		remoteAddr string
+		reused     bool
+	)
+	clientTrace := &httptrace.ClientTrace{
+		DNSStart:          func(_ httptrace.DNSStartInfo) { tr.dnsStart = time.Now() },
+		DNSDone:           func(_ httptrace.DNSDoneInfo) { tr.dnsDone = time.Now() },
+		ConnectStart:      func(_, _ string) { tr.connectStart = time.Now() },
+		ConnectDone:       func(_, _ string, _ error) { tr.connectDone = time.Now() },
+		TLSHandshakeStart: func() { tr.tlsStart = time.Now() },
+		TLSHandshakeDone:  func(_ tls.ConnectionState, _ error) { tr.tlsDone = time.Now() },
+		GotConn: func(info httptrace.GotConnInfo) {
+			tr.gotConn = time.Now()
+			remoteAddr = info.Conn.RemoteAddr().String()
+			reused = info.Reused
+		},
+		GotFirstResponseByte: func() { tr.firstByte = time.Now() },
 	}
+	ctx := context.WithValue(req.Context(), traceKey{}, tr)
+	req = req.WithContext(httptrace.WithClientTrace(ctx, clientTrace))
 
-	colorize := func(s string) string {
-		v := strings.Split(s, "\n")
-		return strings.Join(v, "\n")
+	tr.start = time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
 	}
-
-	fmt.Println()
-
-	switch url.Scheme {
-	case "https":
-		fmt.Printf(colorize(HTTPSTemplate),
-			fmta(t1.Sub(t0)), // dns lookup
-			fmta(t2.Sub(t1)), // tcp connection
-			fmta(t3.Sub(t2)), // tls handshake
-			fmta(t4.Sub(t3)), // server processing
-			fmta(t5.Sub(t4)), // content transfer
-			fmtb(t1.Sub(t0)), // namelookup
-			fmtb(t2.Sub(t0)), // connect
-			fmtb(t3.Sub(t0)), // pretransfer
-			fmtb(t4.Sub(t0)), // starttransfer
-			fmtb(t5.Sub(t0)), // total
-		)
-	case "http":
-		fmt.Printf(colorize(HTTPTemplate),
-			fmta(t1.Sub(t0)), // dns lookup
-			fmta(t3.Sub(t1)), // tcp connection
-			fmta(t4.Sub(t3)), // server processing
-			fmta(t5.Sub(t4)), // content transfer
-			fmtb(t1.Sub(t0)), // namelookup
-			fmtb(t3.Sub(t0)), // connect
-			fmtb(t4.Sub(t0)), // starttransfer
-			fmtb(t5.Sub(t0)), // total
-		)
+	if c.http2 && resp.ProtoMajor != 2 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("server responded with %s instead of HTTP/2", resp.Proto)
 	}
 
-	if c.isRedirect(resp) {
-		loc, err := resp.Location()
-		if err != nil {
-			if err == http.ErrNoLocation {
-				// 30x but no Location to follow, give up.
-				return
-			}
-			log.Fatalf("unable to follow redirect: %v", err)
-		}
-
-		c.redirectsFollowed++
-		if c.redirectsFollowed > maxRedirects {
-			log.Fatalf("maximum number of redirects (%d) followed", maxRedirects)
-		}
-
-		c.visit(loc)
+	result := &Result{
+		URL:          req.URL.String(),
+		Method:       req.Method,
+		Status:       resp.StatusCode,
+		Proto:        resp.Proto,
+		RemoteAddr:   remoteAddr,
+		Proxy:        proxyURL(client, req),
+		Reused:       reused,
+		Header:       resp.Header,
+		Location:     resp.Header.Get("Location"),
+		scheme:       req.URL.Scheme,
+		ServerTiming: parseServerTiming(resp.Header["Server-Timing"]),
 	}
-}
-
-// readResponseBody consumes the body of the response.
-// readResponseBody returns an informational message about the
-// disposition of the response body's contents.
-func (c *Command) readResponseBody(req *http.Request, resp *http.Response) string {
-	if c.isRedirect(resp) || req.Method == http.MethodHead {
-		return ""
+	err = c.readResponseBody(req, resp, result)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
 	}
-
-	msg := "Body discarded"
-	switch c.showBody {
-	case true:
-		data, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			log.Fatalf("failed to read response body: %v", err)
-		}
-		msg = string(data)
-	default:
-		if _, err := io.Copy(ioutil.Discard, resp.Body); err != nil {
-			log.Fatalf("failed to read response body: %v", err)
-		}
+	tr.done = time.Now()
+	result.Timings = tr.timings()
+	if resp.TLS != nil {
+		result.TLS = newTLSInfo(resp.TLS, tr.done)
 	}
-
-	return msg
+	return result, nil
 }
 
-func (c *Command) parseURL(uri string) *url.URL {
+func (c *Command) parseURL(uri string) (*url.URL, error) {
 	if !strings.Contains(uri, "://") && !strings.HasPrefix(uri, "//") {
 		uri = "//" + uri
 	}
 
 	url, err := url.Parse(uri)
 	if err != nil {
-		log.Fatalf("could not parse url %q: %v", uri, err)
+		return nil, fmt.Errorf("could not parse url %q: %v", uri, err)
 	}
 
 	if url.Scheme == "" {
@@ -286,47 +464,65 @@ func (c *Command) parseURL(uri string) *url.URL {
 			url.Scheme += "s"
 		}
 	}
-	return url
+	return url, nil
 }
 
-func (c *Command) headerKeyValue(h string) (string, string) {
+func (c *Command) headerKeyValue(h string) (string, string, error) {
 	i := strings.Index(h, ":")
 	if i == -1 {
-		log.Fatalf("Header '%s' has invalid format, missing ':'", h)
+		return "", "", fmt.Errorf("header '%s' has invalid format, missing ':'", h)
 	}
-	return strings.TrimRight(h[:i], " "), strings.TrimLeft(h[i:], " :")
+	return strings.TrimRight(h[:i], " "), strings.TrimLeft(h[i:], " :"), nil
 }
 
-func (c *Command) isRedirect(resp *http.Response) bool {
-	return resp.StatusCode > 299 && resp.StatusCode < 400
+func (c *Command) isRedirect(status int) bool {
+	return status > 299 && status < 400
 }
 
-func (c *Command) newRequest(method string, url *url.URL, body string) *http.Request {
-	req, err := http.NewRequest(method, url.String(), c.createBody(body))
+func (c *Command) newRequest(method string, url *url.URL, body string) (*http.Request, error) {
+	r, err := c.createBody(body)
 	if err != nil {
-		log.Fatalf("unable to create request: %v", err)
+		return nil, err
+	}
+	req, err := http.NewRequest(method, url.String(), r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request: %v", err)
+	}
+	if c.compressed {
+		req.Header.Set("Accept-Encoding", "gzip, deflate")
+	}
+	// like curl credentials aren't sent to other hosts when redirected.
+	if c.user != "" && c.authHosts[url.Host] {
+		user, password := c.user, ""
+		if i := strings.Index(c.user, ":"); i != -1 {
+			user, password = c.user[:i], c.user[i+1:]
+		}
+		req.SetBasicAuth(user, password)
 	}
 	for _, h := range c.headers {
-		k, v := c.headerKeyValue(h)
+		k, v, err := c.headerKeyValue(h)
+		if err != nil {
+			return nil, err
+		}
 		if strings.EqualFold(k, "host") {
 			req.Host = v
 			continue
 		}
 		req.Header.Add(k, v)
 	}
-	return req
+	return req, nil
 }
 
-func (c *Command) createBody(body string) io.Reader {
+func (c *Command) createBody(body string) (io.Reader, error) {
 	if strings.HasPrefix(body, "@") {
 		filename := body[1:]
 		f, err := os.Open(filename)
 		if err != nil {
-			log.Fatalf("failed to open data file %s: %v", filename, err)
+			return nil, fmt.Errorf("failed to open data file %s: %v", filename, err)
 		}
-		return f
+		return f, nil
 	}
-	return strings.NewReader(body)
+	return strings.NewReader(body), nil
 }
 
 // Aliases are the aliases and name for the command. For instance
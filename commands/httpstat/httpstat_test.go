@@ -0,0 +1,250 @@
+package httpstat
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newCommand() (*Command, *bytes.Buffer, *bytes.Buffer) {
+	stdOut, stdErr := &bytes.Buffer{}, &bytes.Buffer{}
+	return &Command{Name: "httpstat", W: stdOut, WErr: stdErr, AppName: "ron"}, stdOut, stdErr
+}
+
+func newTestHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Test", r.Header.Get("X-Test"))
+		fmt.Fprintf(w, "hello %s", r.Method)
+	})
+	mux.HandleFunc("/redirect/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/redirect/again", http.StatusFound)
+	})
+	mux.HandleFunc("/redirect/again", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	return mux
+}
+
+func decodeResult(t *testing.T, b *bytes.Buffer) *Result {
+	t.Helper()
+	r := &Result{}
+	if err := json.Unmarshal(b.Bytes(), r); err != nil {
+		t.Fatalf("%v: %s", err, b.String())
+	}
+	return r
+}
+
+func TestRunHTTP(t *testing.T) {
+	ts := httptest.NewServer(newTestHandler())
+	defer ts.Close()
+
+	c, stdOut, _ := newCommand()
+	status, err := c.Run([]string{"-v", "-H", "X-Test: yes", ts.URL})
+	if status != 0 || err != nil {
+		t.Fatalf("expected 0 got %d %v", status, err)
+	}
+	for _, s := range []string{"Connected to " + ts.Listener.Addr().String(), "HTTP/1.1 200 OK", "X-Test: yes", "hello GET", "Server Processing", "total:"} {
+		if !strings.Contains(stdOut.String(), s) {
+			t.Errorf("expected %q in %s", s, stdOut.String())
+		}
+	}
+	if strings.Contains(stdOut.String(), "TLS Handshake") {
+		t.Error("expected no TLS handshake for http")
+	}
+}
+
+func TestRunHTTP2(t *testing.T) {
+	ts := httptest.NewUnstartedServer(newTestHandler())
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	c, stdOut, _ := newCommand()
+	status, err := c.Run([]string{"-k", "-o", "json", "-X", "HEAD", ts.URL})
+	if status != 0 || err != nil {
+		t.Fatalf("expected 0 got %d %v", status, err)
+	}
+	r := decodeResult(t, stdOut)
+	if r.Proto != "HTTP/2.0" || r.Status != 200 || r.Method != "HEAD" {
+		t.Errorf("unexpected result %+v", r)
+	}
+	if r.Timings.TLSHandshake <= 0 {
+		t.Errorf("expected a TLS handshake got %+v", r.Timings)
+	}
+	if r.TLS == nil || r.TLS.ALPN != "h2" || len(r.TLS.Certificates) != 1 {
+		t.Fatalf("unexpected tls %+v", r.TLS)
+	}
+	if cert := r.TLS.Certificates[0]; cert.Subject != "O=Acme Co" || cert.DaysRemaining < 365 {
+		t.Errorf("unexpected certificate %+v", cert)
+	}
+}
+
+// writeTestCerts writes the test server's certificate and key to PEM files.
+func writeTestCerts(t *testing.T, dir string, ts *httptest.Server) (string, string) {
+	t.Helper()
+	cert := ts.TLS.Certificates[0]
+	key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key})
+	if err := ioutil.WriteFile(certFile, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestRunTLS(t *testing.T) {
+	clientCerts := 0
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientCerts = len(r.TLS.PeerCertificates)
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "ronhttpstat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeTestCerts(t, dir, ts)
+
+	c, _, _ := newCommand()
+	status, err := c.Run([]string{ts.URL})
+	if status != 1 || err == nil || !strings.Contains(err.Error(), "use -k") {
+		t.Fatalf("expected verification error got %d %v", status, err)
+	}
+
+	c, stdOut, _ := newCommand()
+	status, err = c.Run([]string{"-cacert", certFile, "-cert", certFile, "-key", keyFile, ts.URL})
+	if status != 0 || err != nil {
+		t.Fatalf("expected 0 got %d %v", status, err)
+	}
+	for _, s := range []string{"TLS 1.3, TLS_", "no OCSP stapling", " 0 O=Acme Co (expires ", "SANs: example.com", "Issuer: O=Acme Co"} {
+		if !strings.Contains(stdOut.String(), s) {
+			t.Errorf("expected %q in %s", s, stdOut.String())
+		}
+	}
+	if clientCerts != 1 {
+		t.Errorf("expected a client certificate got %d", clientCerts)
+	}
+
+	c, _, _ = newCommand()
+	status, err = c.Run([]string{"-cacert", keyFile, ts.URL})
+	if status != 1 || err == nil || !strings.Contains(err.Error(), "no certificates found") {
+		t.Errorf("expected cacert error got %d %v", status, err)
+	}
+}
+
+func TestRunRedirects(t *testing.T) {
+	ts := httptest.NewServer(newTestHandler())
+	defer ts.Close()
+
+	c, stdOut, _ := newCommand()
+	status, err := c.Run([]string{"-o", "json", ts.URL + "/redirect/"})
+	if status != 0 || err != nil {
+		t.Fatalf("expected 0 got %d %v", status, err)
+	}
+	r := decodeResult(t, stdOut)
+	if r.URL != ts.URL+"/" || r.Status != 200 {
+		t.Errorf("unexpected result %+v", r)
+	}
+	if len(r.Redirects) != 2 || r.Redirects[0].Status != 302 || r.Redirects[1].URL != ts.URL+"/redirect/again" {
+		t.Errorf("unexpected redirects %+v", r.Redirects)
+	}
+
+	c, stdOut, _ = newCommand()
+	status, err = c.Run([]string{ts.URL + "/redirect/"})
+	if status != 0 || err != nil {
+		t.Fatalf("expected 0 got %d %v", status, err)
+	}
+	for _, want := range []string{"Redirects", "  1  302", "  2  301", "  3  200", ts.URL + "/redirect/again"} {
+		if !strings.Contains(stdOut.String(), want) {
+			t.Errorf("expected waterfall to contain %q got %s", want, stdOut)
+		}
+	}
+
+	c, stdOut, _ = newCommand()
+	status, err = c.Run([]string{ts.URL + "/loop"})
+	if status != 1 || err == nil || !strings.Contains(err.Error(), "maximum number of redirects") {
+		t.Fatalf("expected max redirects error got %d %v", status, err)
+	}
+	if n := strings.Count(stdOut.String(), "HTTP/1.1 302 Found"); n != maxRedirects+1 {
+		t.Errorf("expected %d hops got %d", maxRedirects+1, n)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	ts := httptest.NewServer(newTestHandler())
+	url := ts.URL
+	ts.Close()
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"post body", []string{"-X", "POST", url}, "must supply post body"},
+		{"header", []string{"-H", "X-Test", url}, "missing ':'"},
+		{"url", []string{"http://[::1"}, "could not parse url"},
+		{"data file", []string{"-X", "PUT", "-d", "@nothere", url}, "failed to open data file"},
+		{"connect", []string{url}, "failed to read response"},
+		{"output", []string{"-format", "xml", url}, "unknown output format"},
+		{"flag", []string{"-nope", url}, ""},
+		{"args", []string{}, ""},
+	}
+	for _, tt := range tests {
+		c, _, _ := newCommand()
+		status, err := c.Run(tt.args)
+		if status != 1 {
+			t.Errorf("%s expected status 1 got %d", tt.name, status)
+		}
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s expected no error got %v", tt.name, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%s expected %q got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{"example.com", "https://example.com"},
+		{"example.com:80", "http://example.com:80"},
+		{"//example.com/a", "https://example.com/a"},
+		{"http://example.com", "http://example.com"},
+	}
+	c := &Command{}
+	for _, tt := range tests {
+		u, err := c.parseURL(tt.uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.String() != tt.want {
+			t.Errorf("%s want %s got %s", tt.uri, tt.want, u)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package httpstat
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newOptionsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		user, password, _ := r.BasicAuth()
+		fmt.Fprintf(w, "%s %s user=%s:%s cookie=%s", r.Method, body, user, password, r.Header.Get("Cookie"))
+	})
+	mux.HandleFunc("/gzip", func(w http.ResponseWriter, r *http.Request) {
+		body := strings.Repeat("compress me ", 100)
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			fmt.Fprint(w, body)
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		fmt.Fprint(gz, body)
+		gz.Close()
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/", HttpOnly: true})
+		http.Redirect(w, r, "/echo", http.StatusFound)
+	})
+	return mux
+}
+
+func TestRunBodyOptions(t *testing.T) {
+	ts := httptest.NewServer(newOptionsHandler())
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "ronhttpstat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "body.txt")
+
+	c, stdOut, _ := newCommand()
+	status, err := c.Run([]string{"-o", file, "-d", "hi", "-u", "me:secret", ts.URL + "/echo"})
+	if status != 0 || err != nil {
+		t.Fatalf("expected 0 got %d %v", status, err)
+	}
+	b, _ := ioutil.ReadFile(file)
+	if string(b) != "POST hi user=me:secret cookie=" {
+		t.Errorf("unexpected body %q", b)
+	}
+	if !strings.Contains(stdOut.String(), "Body saved to "+file+" (30 bytes)") {
+		t.Errorf("expected saved message got %s", stdOut.String())
+	}
+
+	c, stdOut, _ = newCommand()
+	status, err = c.Run([]string{"-v", "-X", "patch", "-d", "hi", ts.URL + "/echo"})
+	if status != 0 || err != nil || !strings.Contains(stdOut.String(), "PATCH hi") {
+		t.Errorf("expected PATCH with a body got %d %v %s", status, err, stdOut.String())
+	}
+
+	c, stdOut, _ = newCommand()
+	status, err = c.Run([]string{"-v", "--compressed", "-o", "json", ts.URL + "/gzip"})
+	if status != 0 || err != nil {
+		t.Fatalf("expected 0 got %d %v", status, err)
+	}
+	r := decodeResult(t, stdOut)
+	if r.Size != 1200 || r.Encoding != "gzip" || r.CompressedSize == 0 || r.CompressedSize >= 1200 || !strings.HasPrefix(r.Body, "compress me") {
+		t.Errorf("unexpected result %+v", r)
+	}
+
+	c, stdOut, _ = newCommand()
+	if status, err := c.Run([]string{"-format", "json", ts.URL + "/gzip"}); status != 0 || err != nil {
+		t.Fatalf("expected 0 got %d %v", status, err)
+	}
+	if r := decodeResult(t, stdOut); r.Size != 1200 || r.Encoding != "" {
+		t.Errorf("expected an uncompressed response got %+v", r)
+	}
+}
+
+func TestRunRedirectOptions(t *testing.T) {
+	ts := httptest.NewServer(newOptionsHandler())
+	defer ts.Close()
+
+	c, stdOut, _ := newCommand()
+	status, err := c.Run([]string{"-L=false", ts.URL + "/login"})
+	if status != 0 || err != nil || strings.Count(stdOut.String(), "Connected to") != 1 || !strings.Contains(stdOut.String(), "302 Found") {
+		t.Errorf("expected one hop got %d %v %s", status, err, stdOut.String())
+	}
+
+	// a redirected POST becomes a GET.
+	c, stdOut, _ = newCommand()
+	status, err = c.Run([]string{"-v", "-d", "hi", ts.URL + "/login"})
+	if status != 0 || err != nil || !strings.Contains(stdOut.String(), "GET  user=:") {
+		t.Errorf("expected a GET got %d %v %s", status, err, stdOut.String())
+	}
+}
+
+func TestRunCookies(t *testing.T) {
+	ts := httptest.NewServer(newOptionsHandler())
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "ronhttpstat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	jar := filepath.Join(dir, "cookies.txt")
+
+	c, stdOut, _ := newCommand()
+	status, err := c.Run([]string{"-v", "-b", "a=1; b=2", "-c", jar, ts.URL + "/login"})
+	if status != 0 || err != nil {
+		t.Fatalf("expected 0 got %d %v", status, err)
+	}
+	if !strings.Contains(stdOut.String(), "cookie=a=1; b=2; session=abc") {
+		t.Errorf("expected cookies to be sent got %s", stdOut.String())
+	}
+	b, _ := ioutil.ReadFile(jar)
+	if !strings.Contains(string(b), "#HttpOnly_127.0.0.1\tFALSE\t/\tFALSE\t0\tsession\tabc\n") {
+		t.Errorf("unexpected cookie file %s", b)
+	}
+
+	c, stdOut, _ = newCommand()
+	status, err = c.Run([]string{"-v", "-b", jar, ts.URL + "/echo"})
+	if status != 0 || err != nil || !strings.Contains(stdOut.String(), "session=abc") {
+		t.Errorf("expected cookies from the file got %d %v %s", status, err, stdOut.String())
+	}
+
+	for _, args := range [][]string{{"-n", "2", "-c", jar, ts.URL}, {"--compare", "-cookie-jar", jar, ts.URL, ts.URL}} {
+		c, _, _ = newCommand()
+		status, err = c.Run(args)
+		if status != 1 || err == nil || !strings.Contains(err.Error(), "-c file can't be used") {
+			t.Errorf("%v expected an error got %d %v", args, status, err)
+		}
+	}
+}
+
+func TestRunProtocolOptions(t *testing.T) {
+	ts := httptest.NewUnstartedServer(newOptionsHandler())
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	for _, tt := range []struct {
+		flag string
+		want string
+	}{{"--http1.1", "HTTP/1.1 200 OK"}, {"--http2", "HTTP/2.0 200 OK"}} {
+		c, stdOut, _ := newCommand()
+		status, err := c.Run([]string{"-k", tt.flag, ts.URL + "/echo"})
+		if status != 0 || err != nil || !strings.Contains(stdOut.String(), tt.want) {
+			t.Errorf("%s expected %s got %d %v %s", tt.flag, tt.want, status, err, stdOut.String())
+		}
+	}
+
+	c, _, _ := newCommand()
+	if status, err := c.Run([]string{"--http2", "http://127.0.0.1:1"}); status != 1 || err == nil || !strings.Contains(err.Error(), "requires https") {
+		t.Errorf("expected https error got %d %v", status, err)
+	}
+}
+
+func TestConcurrencyFlag(t *testing.T) {
+	n, file := 1, ""
+	f := &concurrencyFlag{&n, &file}
+	f.Set("4")
+	f.Set("jar.txt")
+	if n != 4 || file != "jar.txt" {
+		t.Errorf("expected 4 jar.txt got %d %s", n, file)
+	}
+}
+
+func TestCookieFile(t *testing.T) {
+	in := "# Netscape HTTP Cookie File\n" +
+		".example.com\tTRUE\t/\tTRUE\t4102444800\tid\t1\n" +
+		"#HttpOnly_example.com\tFALSE\t/app\tFALSE\t0\tsession\tabc\n"
+	cookies, err := readCookies(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cookies) != 2 || !cookies[0].subdomains || !cookies[0].expires.Equal(time.Unix(4102444800, 0)) || !cookies[1].httpOnly {
+		t.Fatalf("unexpected cookies %+v %+v", cookies[0], cookies[1])
+	}
+	jar := newCookieJar()
+	jar.cookies = cookies
+	buf := &bytes.Buffer{}
+	if err := jar.write(buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != in {
+		t.Errorf("expected %q got %q", in, buf.String())
+	}
+	if _, err := readCookies(strings.NewReader("example.com\tTRUE\n")); err == nil {
+		t.Error("expected error")
+	}
+}
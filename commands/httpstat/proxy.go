@@ -0,0 +1,68 @@
+package httpstat
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// traceKey is the context key of a request's trace, it lets transport
+// hooks without an httptrace equivalent record their times.
+type traceKey struct{}
+
+// parseProxy parses a --proxy url, which like curl defaults to http.
+func parseProxy(proxy string) (*url.URL, error) {
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse proxy %q: %v", proxy, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy %q has no host", proxy)
+	}
+	return u, nil
+}
+
+// proxyFunc returns the transports proxy, the environment's unless
+// --proxy is set. Requests over a unix socket aren't proxied.
+func (c *Command) proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	switch {
+	case c.unixSocket != "":
+		return nil, nil
+	case c.proxy != "":
+		u, err := parseProxy(c.proxy)
+		if err != nil {
+			return nil, err
+		}
+		return http.ProxyURL(u), nil
+	}
+	return http.ProxyFromEnvironment, nil
+}
+
+// onProxyConnectResponse records when a proxy answered the CONNECT for a
+// tunnel to a https origin.
+func onProxyConnectResponse(ctx context.Context, _ *url.URL, _ *http.Request, _ *http.Response) error {
+	if tr, ok := ctx.Value(traceKey{}).(*trace); ok {
+		tr.proxyConnectDone = time.Now()
+	}
+	return nil
+}
+
+// proxyURL returns the proxy the transport uses for req without its
+// password.
+func proxyURL(client *http.Client, req *http.Request) string {
+	t, ok := client.Transport.(*http.Transport)
+	if !ok || t.Proxy == nil {
+		return ""
+	}
+	u, err := t.Proxy(req)
+	if err != nil || u == nil {
+		return ""
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,114 @@
+package httpstat
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newConnectProxy starts a proxy which only tunnels CONNECT requests.
+func newConnectProxy(t *testing.T) (*httptest.Server, *int) {
+	connects := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		connects++
+		origin, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		go func() {
+			io.Copy(origin, conn)
+			origin.Close()
+		}()
+		io.Copy(conn, origin)
+		conn.Close()
+	})), &connects
+}
+
+func TestRunProxy(t *testing.T) {
+	ts := httptest.NewTLSServer(newTestHandler())
+	defer ts.Close()
+	proxy, connects := newConnectProxy(t)
+	defer proxy.Close()
+	proxyAddr := proxy.Listener.Addr().String()
+
+	c, stdOut, _ := newCommand()
+	status, err := c.Run([]string{"-k", "-o", "json", "-proxy", proxyAddr, ts.URL})
+	if status != 0 || err != nil {
+		t.Fatalf("expected 0 got %d %v", status, err)
+	}
+	r := decodeResult(t, stdOut)
+	if r.Proxy != "http://"+proxyAddr || r.RemoteAddr != proxyAddr || *connects != 1 {
+		t.Errorf("unexpected result %+v", r)
+	}
+	if r.Timings.ProxyConnect <= 0 || r.Timings.TLSHandshake <= 0 {
+		t.Errorf("expected CONNECT and TLS timings got %+v", r.Timings)
+	}
+
+	c, stdOut, _ = newCommand()
+	if status, err := c.Run([]string{"-k", "-proxy", proxyAddr, ts.URL}); status != 0 || err != nil {
+		t.Fatalf("expected 0 got %d %v", status, err)
+	}
+	if !strings.Contains(stdOut.String(), "Proxy http://"+proxyAddr+", CONNECT tunnel ") {
+		t.Errorf("expected proxy line got %s", stdOut.String())
+	}
+}
+
+func TestRunUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ronhttpstat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "app.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewUnstartedServer(newTestHandler())
+	ts.Listener = l
+	ts.Start()
+	defer ts.Close()
+
+	c, stdOut, _ := newCommand()
+	status, err := c.Run([]string{"-v", "-unix-socket", sock, "http://localhost/health"})
+	if status != 0 || err != nil {
+		t.Fatalf("expected 0 got %d %v", status, err)
+	}
+	for _, s := range []string{"Connected to " + sock, "HTTP/1.1 200 OK", "hello GET"} {
+		if !strings.Contains(stdOut.String(), s) {
+			t.Errorf("expected %q in %s", s, stdOut.String())
+		}
+	}
+
+	c, _, _ = newCommand()
+	if status, err := c.Run([]string{"-unix-socket", sock, "-proxy", "localhost:3128", "http://localhost/"}); status != 1 || err == nil {
+		t.Errorf("expected error got %d %v", status, err)
+	}
+}
+
+func TestParseProxy(t *testing.T) {
+	u, err := parseProxy("proxy:3128")
+	if err != nil || u.String() != "http://proxy:3128" {
+		t.Errorf("expected http://proxy:3128 got %v %v", u, err)
+	}
+	if _, err := parseProxy("http://"); err == nil {
+		t.Error("expected error")
+	}
+}
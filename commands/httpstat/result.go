@@ -0,0 +1,304 @@
+package httpstat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Output formats of the command.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// outputFlag is the -o flag, which is the output format when it is text or
+// json otherwise the file to save the body to.
+type outputFlag struct {
+	format *string
+	file   *string
+}
+
+// Set implements flag.Value
+func (o *outputFlag) Set(v string) error {
+	if v == OutputText || v == OutputJSON {
+		*o.format = v
+		return nil
+	}
+	*o.file = v
+	return nil
+}
+
+// String implements flag.Value
+func (o *outputFlag) String() string {
+	if o.file == nil {
+		return ""
+	}
+	return *o.file
+}
+
+// Result is a single request with its timings. When redirects were
+// followed the final Result holds the earlier hops in Redirects.
+type Result struct {
+	URL        string      `json:"url"`
+	Method     string      `json:"method"`
+	Status     int         `json:"status"`
+	Proto      string      `json:"proto"`
+	RemoteAddr string      `json:"remote_addr"`
+	Proxy      string      `json:"proxy,omitempty"`
+	Reused     bool        `json:"reused,omitempty"`
+	Header     http.Header `json:"headers"`
+	// ServerTiming are the metrics of the Server-Timing headers.
+	ServerTiming []ServerTiming `json:"server_timing,omitempty"`
+	TLS          *TLSInfo       `json:"tls,omitempty"`
+	Location     string         `json:"location,omitempty"`
+	// Size is the size of the body, after decoding with --compressed
+	// which sets the Encoding and CompressedSize.
+	Size           int64     `json:"size"`
+	Encoding       string    `json:"encoding,omitempty"`
+	CompressedSize int64     `json:"compressed_size,omitempty"`
+	Body           string    `json:"body,omitempty"`
+	Timings        Timings   `json:"timings"`
+	Redirects      []*Result `json:"redirects,omitempty"`
+
+	scheme  string
+	body    string
+	bodyMsg string
+}
+
+// Timings are the durations of each phase of a request followed by the
+// cumulative time from the start of the request to the end of each phase.
+type Timings struct {
+	DNSLookup     time.Duration
+	TCPConnection time.Duration
+	// ProxyConnect is the CONNECT of a tunnel through a proxy to a https
+	// origin, the TCP connection is then to the proxy.
+	ProxyConnect     time.Duration
+	TLSHandshake     time.Duration
+	ServerProcessing time.Duration
+	ContentTransfer  time.Duration
+
+	NameLookup    time.Duration
+	Connect       time.Duration
+	PreTransfer   time.Duration
+	StartTransfer time.Duration
+	Total         time.Duration
+}
+
+// MarshalJSON writes the timings in milliseconds.
+func (t Timings) MarshalJSON() ([]byte, error) {
+	m := map[string]float64{}
+	for k, d := range t.fields() {
+		m[k] = milliseconds(*d)
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON reads timings written by MarshalJSON.
+func (t *Timings) UnmarshalJSON(b []byte) error {
+	m := map[string]float64{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	for k, d := range t.fields() {
+		*d = time.Duration(m[k] * float64(time.Millisecond))
+	}
+	return nil
+}
+
+// fields maps the JSON names of the timings to their fields.
+func (t *Timings) fields() map[string]*time.Duration {
+	return map[string]*time.Duration{
+		"dns_lookup":        &t.DNSLookup,
+		"tcp_connection":    &t.TCPConnection,
+		"proxy_connect":     &t.ProxyConnect,
+		"tls_handshake":     &t.TLSHandshake,
+		"server_processing": &t.ServerProcessing,
+		"content_transfer":  &t.ContentTransfer,
+		"namelookup":        &t.NameLookup,
+		"connect":           &t.Connect,
+		"pretransfer":       &t.PreTransfer,
+		"starttransfer":     &t.StartTransfer,
+		"total":             &t.Total,
+	}
+}
+
+// trace holds the times of the httptrace events of a request.
+type trace struct {
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	// proxyConnectDone is set by onProxyConnectResponse from a transport
+	// goroutine, it happens before gotConn.
+	proxyConnectDone time.Time
+	tlsStart         time.Time
+	tlsDone          time.Time
+	gotConn          time.Time
+	firstByte        time.Time
+	done             time.Time
+}
+
+// timings calculates the phases from the trace. Phases which didn't happen,
+// such as DNS for an IP address, are zero.
+func (t *trace) timings() Timings {
+	between := func(a, b time.Time) time.Duration {
+		if a.IsZero() || b.IsZero() {
+			return 0
+		}
+		return b.Sub(a)
+	}
+	return Timings{
+		DNSLookup:        between(t.dnsStart, t.dnsDone),
+		TCPConnection:    between(t.connectStart, t.connectDone),
+		ProxyConnect:     between(t.connectDone, t.proxyConnectDone),
+		TLSHandshake:     between(t.tlsStart, t.tlsDone),
+		ServerProcessing: between(t.gotConn, t.firstByte),
+		ContentTransfer:  between(t.firstByte, t.done),
+		NameLookup:       between(t.start, t.dnsDone),
+		Connect:          between(t.start, t.connectDone),
+		PreTransfer:      between(t.start, t.gotConn),
+		StartTransfer:    between(t.start, t.firstByte),
+		Total:            between(t.start, t.done),
+	}
+}
+
+// writeJSON writes the final result, including its redirects, as one
+// indented JSON document.
+func writeJSON(w io.Writer, results []*Result) error {
+	last := *results[len(results)-1]
+	last.Redirects = results[:len(results)-1]
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&struct {
+		*Result
+		// Elapsed is the cumulative total of all the requests.
+		Elapsed float64 `json:"elapsed"`
+	}{&last, milliseconds(elapsed(results))})
+}
+
+// elapsed is the cumulative total time of the results.
+func elapsed(results []*Result) time.Duration {
+	var d time.Duration
+	for _, r := range results {
+		d += r.Timings.Total
+	}
+	return d
+}
+
+// writeText writes the status line, headers, body message and timings
+// template of each result followed by a waterfall of the redirects.
+func writeText(w io.Writer, results []*Result) {
+	for _, r := range results {
+		r.writeText(w)
+	}
+	if len(results) > 1 {
+		writeWaterfall(w, results)
+	}
+}
+
+// waterfallWidth is the width of the bars of the waterfall.
+const waterfallWidth = 40
+
+// writeWaterfall writes each hop with when it started and finished
+// relative to the first request, and a bar of when it happened.
+func writeWaterfall(w io.Writer, results []*Result) {
+	total := elapsed(results)
+	fmt.Fprintf(w, "\nRedirects\n")
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "  #\tSTATUS\tURL\tSTART\tTOTAL\tCUMULATIVE\t")
+	var start time.Duration
+	for i, r := range results {
+		bar := strings.Repeat(" ", waterfallWidth)
+		if total > 0 {
+			from := int(int64(start) * waterfallWidth / int64(total))
+			n := int(int64(r.Timings.Total) * waterfallWidth / int64(total))
+			if n < 1 {
+				n = 1
+			}
+			if from+n > waterfallWidth {
+				from = waterfallWidth - n
+			}
+			bar = strings.Repeat(" ", from) + strings.Repeat("=", n) + strings.Repeat(" ", waterfallWidth-from-n)
+		}
+		end := start + r.Timings.Total
+		fmt.Fprintf(tw, "  %d\t%d\t%s\t%dms\t%dms\t%dms\t|%s|\n", i+1, r.Status, r.URL,
+			int(start/time.Millisecond), int(r.Timings.Total/time.Millisecond), int(end/time.Millisecond), bar)
+		start = end
+	}
+	tw.Flush()
+}
+
+func (r *Result) writeText(w io.Writer) {
+	fmt.Fprintf(w, "\nConnected to %s\n", r.RemoteAddr)
+	if r.Proxy != "" {
+		fmt.Fprintf(w, "Proxy %s", r.Proxy)
+		if r.Timings.ProxyConnect > 0 {
+			fmt.Fprintf(w, ", CONNECT tunnel %dms", int(r.Timings.ProxyConnect/time.Millisecond))
+		}
+		fmt.Fprintln(w)
+	}
+	if r.TLS != nil {
+		r.TLS.writeText(w)
+	}
+	fmt.Fprintf(w, "\n%s %d %s\n", r.Proto, r.Status, http.StatusText(r.Status))
+
+	names := make([]string, 0, len(r.Header))
+	for k := range r.Header {
+		names = append(names, k)
+	}
+	sort.Sort(headers(names))
+	for _, k := range names {
+		fmt.Fprintf(w, "%s: %s\n", k, strings.Join(r.Header[k], ","))
+	}
+
+	if r.bodyMsg != "" {
+		fmt.Fprintf(w, "\n%s (%s)\n", r.bodyMsg, r.sizeText())
+	}
+
+	fmta := func(d time.Duration) string {
+		return fmt.Sprintf("%7dms", int(d/time.Millisecond))
+	}
+
+	fmtb := func(d time.Duration) string {
+		return fmt.Sprintf("%-9sms", strconv.Itoa(int(d/time.Millisecond)))
+	}
+
+	fmt.Fprintln(w)
+
+	t := r.Timings
+	switch r.scheme {
+	case "https":
+		fmt.Fprintf(w, HTTPSTemplate,
+			fmta(t.DNSLookup),
+			fmta(t.TCPConnection),
+			fmta(t.TLSHandshake),
+			fmta(t.ServerProcessing),
+			fmta(t.ContentTransfer),
+			fmtb(t.NameLookup),
+			fmtb(t.Connect),
+			fmtb(t.PreTransfer),
+			fmtb(t.StartTransfer),
+			fmtb(t.Total),
+		)
+	default:
+		fmt.Fprintf(w, HTTPTemplate,
+			fmta(t.DNSLookup),
+			fmta(t.TCPConnection),
+			fmta(t.ServerProcessing),
+			fmta(t.ContentTransfer),
+			fmtb(t.NameLookup),
+			fmtb(t.Connect),
+			fmtb(t.StartTransfer),
+			fmtb(t.Total),
+		)
+	}
+	r.writeServerTiming(w)
+}
@@ -0,0 +1,73 @@
+package httpstat
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTraceTimings(t *testing.T) {
+	start := time.Now()
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+	tr := &trace{
+		start:        start,
+		connectStart: at(1),
+		connectDone:  at(3),
+		gotConn:      at(4),
+		firstByte:    at(10),
+		done:         at(12),
+	}
+	got := tr.timings()
+	want := Timings{
+		TCPConnection:    2 * time.Millisecond,
+		ServerProcessing: 6 * time.Millisecond,
+		ContentTransfer:  2 * time.Millisecond,
+		Connect:          3 * time.Millisecond,
+		PreTransfer:      4 * time.Millisecond,
+		StartTransfer:    10 * time.Millisecond,
+		Total:            12 * time.Millisecond,
+	}
+	if got != want {
+		t.Errorf("want %+v got %+v", want, got)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	results := []*Result{
+		{URL: "http://a/", Status: 302, Location: "http://b/"},
+		{URL: "http://b/", Status: 200, Timings: Timings{Total: 1500 * time.Microsecond}},
+	}
+	buf := &bytes.Buffer{}
+	if err := writeJSON(buf, results); err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		URL       string             `json:"url"`
+		Status    int                `json:"status"`
+		Timings   map[string]float64 `json:"timings"`
+		Redirects []struct {
+			URL      string `json:"url"`
+			Location string `json:"location"`
+		} `json:"redirects"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.URL != "http://b/" || got.Status != 200 || got.Timings["total"] != 1.5 {
+		t.Errorf("unexpected result %+v", got)
+	}
+	if len(got.Redirects) != 1 || got.Redirects[0].Location != "http://b/" {
+		t.Errorf("expected one redirect got %+v", got.Redirects)
+	}
+	decoded := &Result{}
+	if err := json.Unmarshal(buf.Bytes(), decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Timings.Total != 1500*time.Microsecond {
+		t.Errorf("expected 1.5ms got %s", decoded.Timings.Total)
+	}
+	if results[1].Redirects != nil {
+		t.Error("expected results to be unchanged")
+	}
+}
@@ -0,0 +1,109 @@
+package httpstat
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// ServerTiming is a metric of a Server-Timing response header, Duration
+// is in milliseconds and zero when the server didn't report one.
+type ServerTiming struct {
+	Name        string  `json:"name"`
+	Duration    float64 `json:"duration,omitempty"`
+	Description string  `json:"description,omitempty"`
+}
+
+// parseServerTiming parses the metrics of Server-Timing header values
+// such as db;dur=53;desc="Database", app;dur=47.2. Invalid parameters are
+// ignored.
+func parseServerTiming(values []string) []ServerTiming {
+	timings := []ServerTiming{}
+	for _, v := range values {
+		for _, metric := range splitQuoted(v, ',') {
+			params := splitQuoted(metric, ';')
+			name := strings.TrimSpace(params[0])
+			if name == "" {
+				continue
+			}
+			t := ServerTiming{Name: name}
+			for _, p := range params[1:] {
+				i := strings.Index(p, "=")
+				if i == -1 {
+					continue
+				}
+				key, value := strings.ToLower(strings.TrimSpace(p[:i])), strings.TrimSpace(p[i+1:])
+				if unquoted, err := strconv.Unquote(value); err == nil {
+					value = unquoted
+				}
+				switch key {
+				case "dur":
+					if d, err := strconv.ParseFloat(value, 64); err == nil {
+						t.Duration = d
+					}
+				case "desc":
+					t.Description = value
+				}
+			}
+			timings = append(timings, t)
+		}
+	}
+	return timings
+}
+
+// splitQuoted splits s on sep outside of double quotes.
+func splitQuoted(s string, sep rune) []string {
+	parts := []string{}
+	quoted, escaped, start := false, false, 0
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && quoted:
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// writeServerTiming breaks server processing down into the metrics the
+// server reported, with the remainder it didn't account for.
+func (r *Result) writeServerTiming(w io.Writer) {
+	if len(r.ServerTiming) == 0 {
+		return
+	}
+	processing := milliseconds(r.Timings.ServerProcessing)
+	reported := 0.0
+	for _, t := range r.ServerTiming {
+		reported += t.Duration
+	}
+	// the percentages are meaningless when the server reports more time
+	// than was measured, e.g. with overlapping metrics.
+	percent := func(ms float64) string {
+		if processing <= 0 || reported > processing {
+			return ""
+		}
+		return fmt.Sprintf("%.0f%%", ms/processing*100)
+	}
+	fmt.Fprintf(w, "\nServer Processing %dms, Server-Timing:\n", int(r.Timings.ServerProcessing/time.Millisecond))
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, t := range r.ServerTiming {
+		if t.Duration == 0 {
+			fmt.Fprintf(tw, "  %s\t-\t\t%s\n", t.Name, t.Description)
+			continue
+		}
+		fmt.Fprintf(tw, "  %s\t%.2fms\t%s\t%s\n", t.Name, t.Duration, percent(t.Duration), t.Description)
+	}
+	if rest := processing - reported; reported > 0 && rest > 0 {
+		fmt.Fprintf(tw, "  rest\t%.2fms\t%s\t%s\n", rest, percent(rest), "network and unreported")
+	}
+	tw.Flush()
+}
@@ -0,0 +1,49 @@
+package httpstat
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseServerTiming(t *testing.T) {
+	got := parseServerTiming([]string{
+		`db;dur=53;desc="Database, primary", app;dur=47.2`,
+		`cache;desc=miss, bad;dur=x, ;dur=1`,
+	})
+	want := []ServerTiming{
+		{Name: "db", Duration: 53, Description: "Database, primary"},
+		{Name: "app", Duration: 47.2},
+		{Name: "cache", Description: "miss"},
+		{Name: "bad"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v got %+v", want, got)
+	}
+}
+
+func TestWriteServerTiming(t *testing.T) {
+	r := &Result{
+		Timings: Timings{ServerProcessing: 100 * time.Millisecond},
+		ServerTiming: []ServerTiming{
+			{Name: "db", Duration: 60, Description: "Database"},
+			{Name: "cache", Description: "miss"},
+		},
+	}
+	b := &bytes.Buffer{}
+	r.writeServerTiming(b)
+	for _, want := range []string{"Server Processing 100ms", "db     60.00ms  60%  Database", "cache  -", "rest   40.00ms  40%  network and unreported"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("expected %q in %s", want, b)
+		}
+	}
+
+	r.ServerTiming = []ServerTiming{{Name: "app", Duration: 150}}
+	b.Reset()
+	r.writeServerTiming(b)
+	if strings.Contains(b.String(), "%") || strings.Contains(b.String(), "rest") {
+		t.Errorf("expected no percentages when over the measured time got %s", b)
+	}
+}
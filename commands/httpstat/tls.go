@@ -0,0 +1,147 @@
+package httpstat
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// TLSInfo describes the negotiated TLS session of a request.
+type TLSInfo struct {
+	Version      string      `json:"version"`
+	CipherSuite  string      `json:"cipher_suite"`
+	ALPN         string      `json:"alpn,omitempty"`
+	OCSPStapled  bool        `json:"ocsp_stapled"`
+	OCSPStatus   string      `json:"ocsp_status,omitempty"`
+	Certificates []*CertInfo `json:"certificates"`
+}
+
+// CertInfo describes a certificate of the peer's chain.
+type CertInfo struct {
+	Subject       string    `json:"subject"`
+	SANs          []string  `json:"sans,omitempty"`
+	Issuer        string    `json:"issuer"`
+	NotBefore     time.Time `json:"not_before"`
+	NotAfter      time.Time `json:"not_after"`
+	DaysRemaining int       `json:"days_remaining"`
+}
+
+// tlsVersions are the names of the TLS versions.
+var tlsVersions = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+// newTLSInfo describes state with the days remaining counted from now.
+func newTLSInfo(state *tls.ConnectionState, now time.Time) *TLSInfo {
+	info := &TLSInfo{
+		Version:     tlsVersions[state.Version],
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ALPN:        state.NegotiatedProtocol,
+		OCSPStapled: len(state.OCSPResponse) > 0,
+	}
+	if info.Version == "" {
+		info.Version = fmt.Sprintf("0x%04x", state.Version)
+	}
+	for _, cert := range state.PeerCertificates {
+		sans := append([]string{}, cert.DNSNames...)
+		for _, ip := range cert.IPAddresses {
+			sans = append(sans, ip.String())
+		}
+		info.Certificates = append(info.Certificates, &CertInfo{
+			Subject:       cert.Subject.String(),
+			SANs:          sans,
+			Issuer:        cert.Issuer.String(),
+			NotBefore:     cert.NotBefore,
+			NotAfter:      cert.NotAfter,
+			DaysRemaining: int(cert.NotAfter.Sub(now).Hours() / 24),
+		})
+	}
+	if info.OCSPStapled && len(state.PeerCertificates) > 1 {
+		info.OCSPStatus = ocspStatus(state.OCSPResponse, state.PeerCertificates[1])
+	}
+	return info
+}
+
+// ocspStatus parses a stapled OCSP response signed by issuer.
+func ocspStatus(b []byte, issuer *x509.Certificate) string {
+	resp, err := ocsp.ParseResponse(b, issuer)
+	if err != nil {
+		return fmt.Sprintf("invalid: %v", err)
+	}
+	switch resp.Status {
+	case ocsp.Good:
+		return "good"
+	case ocsp.Revoked:
+		return "revoked " + resp.RevokedAt.Format("2006-01-02")
+	}
+	return "unknown"
+}
+
+// tlsConfig creates the TLS config for requests to host, loading the CA
+// and client certificates given on the command line.
+func (c *Command) tlsConfig(host string) (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: c.insecure,
+	}
+	if c.caCert != "" {
+		b, err := ioutil.ReadFile(c.caCert)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificates found in %s", c.caCert)
+		}
+	}
+	if c.cert != "" {
+		// like curl the key can be in the cert file.
+		key := c.key
+		if key == "" {
+			key = c.cert
+		}
+		cert, err := tls.LoadX509KeyPair(c.cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// writeText writes the session followed by the certificate chain.
+func (t *TLSInfo) writeText(w io.Writer) {
+	session := []string{t.Version, t.CipherSuite}
+	if t.ALPN != "" {
+		session = append(session, "ALPN "+t.ALPN)
+	}
+	switch {
+	case t.OCSPStatus != "":
+		session = append(session, "OCSP stapled "+t.OCSPStatus)
+	case t.OCSPStapled:
+		session = append(session, "OCSP stapled")
+	default:
+		session = append(session, "no OCSP stapling")
+	}
+	fmt.Fprintf(w, "\n%s\n", strings.Join(session, ", "))
+	for i, cert := range t.Certificates {
+		expires := fmt.Sprintf("expires %s, %d days remaining", cert.NotAfter.Format("2006-01-02"), cert.DaysRemaining)
+		if cert.DaysRemaining < 0 {
+			expires = fmt.Sprintf("EXPIRED %s, %d days ago", cert.NotAfter.Format("2006-01-02"), -cert.DaysRemaining)
+		}
+		fmt.Fprintf(w, "%2d %s (%s)\n", i, cert.Subject, expires)
+		if len(cert.SANs) > 0 {
+			fmt.Fprintf(w, "   SANs: %s\n", strings.Join(cert.SANs, ", "))
+		}
+		fmt.Fprintf(w, "   Issuer: %s\n", cert.Issuer)
+	}
+}
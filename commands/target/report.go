@@ -0,0 +1,84 @@
+package target
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/upsight/ron/target"
+)
+
+// report is a format and the path it is written to, an empty path being
+// stdout.
+type report struct {
+	format string
+	path   string
+}
+
+type reports []report
+
+// Set implements flag.Value
+func (r *reports) Set(v string) error {
+	format, path := v, ""
+	if i := strings.Index(v, "="); i >= 0 {
+		format, path = v[:i], v[i+1:]
+	}
+	switch format {
+	case target.ReportJUnit:
+		if path == "" {
+			return fmt.Errorf("%s report requires a path such as junit=report.xml", format)
+		}
+		// resolve before changing to the directory of any found config.
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		path = abs
+	case target.ReportGitHub:
+	default:
+		return fmt.Errorf("unknown report %q, expected junit or github", format)
+	}
+	*r = append(*r, report{format: format, path: path})
+	return nil
+}
+
+// String implements flag.Value
+func (r reports) String() string {
+	var o []string
+	for _, v := range r {
+		if v.path == "" {
+			o = append(o, v.format)
+			continue
+		}
+		o = append(o, v.format+"="+v.path)
+	}
+	return strings.Join(o, ",")
+}
+
+// write writes each report for the run events.
+func (r reports) write(stdOut io.Writer, events []*target.Event) error {
+	for _, v := range r {
+		w := stdOut
+		if v.path != "" {
+			f, err := os.Create(v.path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		var err error
+		switch v.format {
+		case target.ReportJUnit:
+			err = target.WriteJUnit(w, events)
+		case target.ReportGitHub:
+			err = target.WriteAnnotations(w, events)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
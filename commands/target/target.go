@@ -1,16 +1,28 @@
 package target
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
 
 	"github.com/upsight/ron/execute"
 	"github.com/upsight/ron/target"
+	"github.com/upsight/ron/watch"
 )
 
+// logDir is where a log of each run is written, relative to the directory
+// of the found config.
+var logDir = filepath.Join(target.ConfigDirName, "logs")
+
+// logKeep is the number of run logs kept by default.
+const logKeep = 20
+
 // Command ...
 type Command struct {
 	W       io.Writer
@@ -71,6 +83,23 @@ func (c *Command) Run(args []string) (int, error) {
 	If no identity file is provided, the users local ssh agent will be attempted. You can add
 	keys with ssh-add.
 
+	Remote commands are only run with a pseudo terminal when ron is run interactively,
+	which keeps stdout and stderr separate when output is captured. Set pty: true or
+	pty: false on a remote or a target to override this, the target taking precedence.
+
+	Setting sudo or sudo_user on a remote or a target will run the target cmd through sudo,
+	the target settings taking precedence. Use -ask_sudo_pass to be prompted once for the
+	password used on every host, otherwise sudo must not require one.
+
+		remotes:
+			production:
+				-
+					host: exampleprod.com
+					port: 22
+					user: test
+					sudo: true
+					sudo_user: deploy
+
 	env values prefixed with a +(subject to change) will be executed and set to the os environment
 	prior to target execution.
 
@@ -93,6 +122,103 @@ func (c *Command) Run(args []string) (int, error) {
 					- prep
 				cmd: |
 					echo $APP
+
+	A shell can be set for all targets in a file or on a single target, the default being
+	bash -e, or sh -e when bash isn't installed and inside containers. Shells and interpreters
+	such as sh, zsh, python3 or node are given the cmd inline, any other command is given it as
+	a script file argument.
+
+		shell: bash -euo pipefail
+		targets:
+			hello:
+				shell: python3
+				cmd: |
+					print("hello")
+
+	targets with an image run their cmd inside a container using the local container runtime,
+	docker unless RON_CONTAINER_RUNTIME is set. The current directory is mounted at the same
+	path and used as the working directory unless workdir is set. Only the envs declared in the
+	ron configs are passed into the container, the rest of the host environment is not. The cmd
+	is run with sh -e inside the container unless shell is set, as not every image has bash.
+
+		targets:
+			test:
+				image: golang:1.21
+				volumes:
+					- $HOME/go/pkg/mod:/go/pkg/mod
+				cmd: |
+					go test ./...
+
+	targets run with -remotes can upload local files before the cmd is run and
+	download remote files after it finishes. Upload src is a local glob and download
+	src is expanded by the remote shell. File modes are preserved.
+
+		targets:
+			deploy:
+				upload:
+					- src: bin/app-*
+					  dst: /opt/app/
+				cmd: |
+					/opt/app/install.sh
+				download:
+					- src: /var/log/app/install.log
+					  dst: logs/
+
+	With -watch targets are run again whenever a file matching the watch globs of the
+	target, or of its before and after targets, changes. Without any globs every file
+	is watched. Files in .gitignore, the .ron directory and the -log_dir directory are
+	ignored. Before the next run starts a run still in progress is cancelled, the process
+	group of its running cmd is sent SIGTERM and killed if it has not exited after the
+	-grace period, and its background targets are stopped and torn down.
+
+		targets:
+			test:
+				watch:
+					- "*.go"
+					- go.mod
+				cmd: |
+					go test ./...
+
+	wait_for runs readiness probes before the cmd, retrying every interval (default 1s) until they all
+	pass or the timeout (default 30s) is reached. tcp waits for a host:port to accept connections, http
+	for a url to respond with status or any 2xx or 3xx, file for a path to exist and cmd for a command
+	to exit 0. Probes are only run for local targets.
+
+		targets:
+			integration:
+				before:
+					- db
+				wait_for:
+					tcp: localhost:$DB_PORT
+					http: http://localhost:8080/health
+					status: 200
+					timeout: 1m
+					interval: 500ms
+				cmd: |
+					go test -tags integration ./...
+
+	background targets start their cmd without waiting for it to exit, keeping it running while the
+	targets that depend on it run. Once the top level run finishes or fails each background target has
+	its process group sent SIGTERM, anything left in the group once the cmd exits is killed, and its
+	teardown target, if set, is run. Output is prefixed with the target name and stdin is empty, leaving
+	the terminal to the other targets.
+
+		targets:
+			postgres:
+				background: true
+				teardown: postgres_clean
+				cmd: |
+					docker run --rm --name ron-pg -p 5432:5432 postgres:16
+			postgres_clean:
+				cmd: |
+					docker rm -f ron-pg || true
+			test:
+				before:
+					- postgres
+				wait_for:
+					tcp: localhost:5432
+				cmd: |
+					go test ./...
 	`)
 	var listEnvs bool
 	f.BoolVar(&listEnvs, "envs", false, "List the initialized environment variables.")
@@ -106,16 +232,36 @@ func (c *Command) Run(args []string) (int, error) {
 	f.BoolVar(&listTargetsClean, "list_clean", false, "List the available targets for bash completion.")
 	var remoteEnv string
 	f.StringVar(&remoteEnv, "remotes", "", "The remote target environment to run the target on.")
+	var output string
+	f.StringVar(&output, "output", execute.OutputPrefixed, `How output from remote hosts is shown, "prefixed" writes lines as they arrive, "grouped" writes each hosts output once it finishes.`)
+	var askSudoPass bool
+	f.BoolVar(&askSudoPass, "ask_sudo_pass", false, "Prompt once for the sudo password used by sudo targets on all remote hosts.")
 	var verbose bool
 	f.BoolVar(&verbose, "verbose", false, "When used with list be verbose.")
 	var verboseShort bool
 	f.BoolVar(&verboseShort, "v", false, "When used with list be verbose.")
 	f.BoolVar(&execute.Debug, "debug", false, "Debug the target command being run")
+	var runLogDir string
+	f.StringVar(&runLogDir, "log_dir", logDir, "Directory to write a json lines log of each run to, empty disables the log.")
+	var runLogKeep int
+	f.IntVar(&runLogKeep, "log_keep", logKeep, "The number of run logs to keep including this run's, older ones are removed.")
+	var reports reports
+	f.Var(&reports, "report", `Write a report once targets finish, "junit=path.xml" for JUnit XML or "github" for GitHub Actions annotations on stdout. Can be repeated.`)
+	var watchTargets bool
+	f.BoolVar(&watchTargets, "watch", false, "Run the targets again when files matching their watch globs, or any file when none are set, change.")
+	var watchIgnore string
+	f.StringVar(&watchIgnore, "watch_ignore", "", "Comma separated globs of files to ignore in watch mode, in addition to .gitignore.")
+	var last bool
+	f.BoolVar(&last, "last", false, "Show the timing summary of the previous run.")
+	f.DurationVar(&execute.GracePeriod, "grace", execute.GracePeriod, "How long an interrupted target is given to exit before it is killed.")
 	f.Parse(args)
 	if len(args) == 0 {
 		f.Usage()
 		return 1, nil
 	}
+	if runLogKeep < 0 {
+		return 1, fmt.Errorf("-log_keep must be 0 or more, got %d", runLogKeep)
+	}
 
 	// FIXME When using globs and os.Args without quoting the target, the glob will match
 	// any files in the directory.
@@ -134,6 +280,18 @@ func (c *Command) Run(args []string) (int, error) {
 		// directory to that folder so Ron targets run from the expected place.
 		os.Chdir(foundConfigDir)
 	}
+	if last {
+		path, err := target.LastRunLog(runLogDir)
+		if err != nil {
+			return 1, err
+		}
+		events, err := target.ReadRunLog(path)
+		if err != nil {
+			return 1, err
+		}
+		target.Summary(c.W, events)
+		return 0, nil
+	}
 	// Create targets
 	targetConfig, err := target.NewConfigs(configs, remoteEnv, c.W, c.WErr)
 	if err != nil {
@@ -162,12 +320,40 @@ func (c *Command) Run(args []string) (int, error) {
 		return 0, nil
 	}
 
+	targetConfig.Output = output
+	if askSudoPass {
+		targetConfig.SudoPassword, err = execute.ReadPassword("sudo password: ", c.WErr)
+		if err != nil {
+			return 1, err
+		}
+	}
+
 	// Create make runner
 	m, err := target.NewMake(targetConfig)
 	if err != nil {
 		return 1, err
 	}
-	err = m.Run(f.Args()...)
+	runLog := &target.RunLog{}
+	if runLogDir != "" {
+		runLog, err = target.NewRunLog(runLogDir)
+		if err != nil {
+			return 1, err
+		}
+		if err := runLog.Prune(runLogKeep); err != nil {
+			return 1, err
+		}
+	}
+	targetConfig.Recorders = append(targetConfig.Recorders, runLog)
+	if watchTargets {
+		err = watchRun(m, watchIgnore, runLogDir, f.Args()...)
+	} else {
+		err = m.Run(f.Args()...)
+	}
+	target.Summary(c.WErr, runLog.Events)
+	if rerr := reports.write(c.W, runLog.Events); rerr != nil && err == nil {
+		err = rerr
+	}
+	runLog.Close()
 	if err != nil {
 		return 1, err
 	}
@@ -175,6 +361,41 @@ func (c *Command) Run(args []string) (int, error) {
 	return 0, nil
 }
 
+// watchContext returns the context watch mode runs until, which is done
+// once interrupted.
+var watchContext = func() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// watchRun runs the targets in watch mode until interrupted. The ron
+// config directory and the run log directory are never watched as each
+// run writes to them.
+func watchRun(m *target.Make, ignore string, runLogDir string, names ...string) error {
+	w, err := watch.New(".")
+	if err != nil {
+		return err
+	}
+	w.Include = m.WatchPatterns(names...)
+	w.Ignore.Add("/" + target.ConfigDirName + "/")
+	if abs, err := filepath.Abs(runLogDir); err == nil && runLogDir != "" {
+		if rel, err := filepath.Rel(w.Root, abs); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			w.Ignore.Add("/" + filepath.ToSlash(rel) + "/")
+		}
+	}
+	for _, p := range strings.Split(ignore, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			w.Exclude = append(w.Exclude, p)
+		}
+	}
+	ctx, stop := watchContext()
+	defer stop()
+	err = m.Watch(ctx, w, names...)
+	if err == context.Canceled {
+		return nil
+	}
+	return err
+}
+
 // Aliases are the aliases and name for the command. For instance
 // a command can have a long form and short form.
 func (c *Command) Aliases() map[string]struct{} {
@@ -2,10 +2,15 @@ package target
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/upsight/ron/target"
 )
@@ -19,6 +24,15 @@ var (
 	}
 )
 
+// testdataDir is absolute as running targets changes to the directory of
+// any found config.
+var testdataDir, _ = filepath.Abs("testdata")
+
+func init() {
+	// keep tests from writing run logs into the source tree.
+	logDir = ""
+}
+
 func TestRonRunTarget(t *testing.T) {
 	stdOut := &bytes.Buffer{}
 	stdErr := &bytes.Buffer{}
@@ -112,3 +126,153 @@ func TestRonRunTargetLoadOverrideErr(t *testing.T) {
 		t.Fatal("expected err")
 	}
 }
+
+func TestRonRunTargetLast(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ronlogs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	yaml := "--yaml=" + filepath.Join(testdataDir, "target_test.yaml")
+
+	stdOut := &bytes.Buffer{}
+	stdErr := &bytes.Buffer{}
+	c := &Command{W: stdOut, WErr: stdErr}
+	status, err := c.Run([]string{"-last", "-log_dir=" + dir, yaml})
+	if status == 0 || err == nil {
+		t.Fatalf("expected no run logs error got %d %v", status, err)
+	}
+
+	status, err = c.Run([]string{"-log_dir=" + dir, yaml, "prep"})
+	if status != 0 || err != nil {
+		t.Fatalf("expected 0 got %d %v", status, err)
+	}
+	if !strings.Contains(stdErr.String(), "target_test:prep") {
+		t.Errorf("expected timing summary got %s", stdErr.String())
+	}
+
+	stdOut.Reset()
+	status, err = c.Run([]string{"-last", "-log_dir=" + dir, yaml})
+	if status != 0 || err != nil {
+		t.Fatalf("expected 0 got %d %v", status, err)
+	}
+	if stdOut.String() != stdErr.String() {
+		t.Errorf("expected last summary %s got %s", stdErr.String(), stdOut.String())
+	}
+}
+
+func TestRonRunTargetLogKeep(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ronlogs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	yaml := "--yaml=" + filepath.Join(testdataDir, "target_test.yaml")
+
+	c := &Command{W: &bytes.Buffer{}, WErr: &bytes.Buffer{}}
+	status, err := c.Run([]string{"-log_dir=" + dir, "-log_keep=-1", yaml, "hello"})
+	if status == 0 || err == nil {
+		t.Fatalf("expected negative -log_keep error got %d %v", status, err)
+	}
+	for i := 0; i < 2; i++ {
+		status, err = c.Run([]string{"-log_dir=" + dir, "-log_keep=0", yaml, "hello"})
+		if status != 0 || err != nil {
+			t.Fatalf("expected 0 got %d %v", status, err)
+		}
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if len(files) != 1 {
+		t.Errorf("expected only the last run log got %v", files)
+	}
+	stdOut := &bytes.Buffer{}
+	c = &Command{W: stdOut, WErr: &bytes.Buffer{}}
+	status, err = c.Run([]string{"-last", "-log_dir=" + dir, yaml})
+	if status != 0 || err != nil || !strings.Contains(stdOut.String(), "target_test:hello") {
+		t.Errorf("expected last summary got %d %v %s", status, err, stdOut.String())
+	}
+}
+
+func TestRonRunTargetReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ronreport")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	junit := filepath.Join(dir, "junit.xml")
+
+	stdOut := &bytes.Buffer{}
+	stdErr := &bytes.Buffer{}
+	c := &Command{W: stdOut, WErr: stdErr}
+	status, err := c.Run([]string{"--yaml=" + filepath.Join(testdataDir, "target_test.yaml"), "-report=junit=" + junit, "prep"})
+	if status != 0 || err != nil {
+		t.Fatalf("expected 0 got %d %v", status, err)
+	}
+	b, err := ioutil.ReadFile(junit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`tests="3"`, `failures="0"`, `name="target_test:prep"`} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("expected %s in report got %s", want, b)
+		}
+	}
+}
+
+func TestRonRunTargetWatchLogs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ronwatch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	yaml := filepath.Join(dir, "ron.yaml")
+	if err := ioutil.WriteFile(yaml, []byte("targets:\n  hi:\n    cmd: echo hi\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	prevWatchContext := watchContext
+	defer func() { watchContext = prevWatchContext }()
+	watchContext = func() (context.Context, context.CancelFunc) {
+		// stop as an interrupt would once a change could have been seen.
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(1500*time.Millisecond, cancel)
+		return ctx, cancel
+	}
+
+	for _, runLogDir := range []string{filepath.Join(target.ConfigDirName, "logs"), "logs"} {
+		stdOut := &bytes.Buffer{}
+		stdErr := &bytes.Buffer{}
+		c := &Command{W: stdOut, WErr: stdErr}
+		status, err := c.Run([]string{"--yaml=" + yaml, "-log_dir=" + runLogDir, "-watch", "hi"})
+		if status != 0 || err != nil {
+			t.Fatalf("expected 0 got %d %v", status, err)
+		}
+		if _, err := target.LastRunLog(runLogDir); err != nil {
+			t.Errorf("expected a run log in %s got %v", runLogDir, err)
+		}
+		if n := strings.Count(stdOut.String(), "hi\n"); n != 1 || strings.Contains(stdErr.String(), "changed") {
+			t.Errorf("expected a single run with -log_dir=%s got %d %s", runLogDir, n, stdErr.String())
+		}
+	}
+}
+
+func TestReportsSet(t *testing.T) {
+	var r reports
+	for _, v := range []string{"junit", "nope=x", "teamcity"} {
+		if err := r.Set(v); err == nil {
+			t.Errorf("expected error for %s", v)
+		}
+	}
+	if err := r.Set("github"); err != nil {
+		t.Fatal(err)
+	}
+	if r.String() != "github" {
+		t.Errorf("expected github got %s", r.String())
+	}
+}
@@ -73,46 +73,196 @@ The http stat (hs,httpstat) command will give response times broken up by task.
 	                        connect:33       ms         |                  |
 	                                      starttransfer:89       ms        |
 	                                                                 total:90       ms
+
+For https the server's certificate is verified unless -k is given, -cacert verifies it with the CA
+certificates in a PEM file instead of the system's and -cert and -key send a client certificate. The
+negotiated TLS version, cipher suite, ALPN protocol, OCSP stapling and the peer's certificate chain
+are printed after the address connected to.
+
+	$ ron hs https://google.com
+	Connected to 142.250.191.46:443
+
+	TLS 1.3, TLS_AES_128_GCM_SHA256, ALPN h2, OCSP stapled good
+	 0 CN=*.google.com (expires 2026-12-22, 64 days remaining)
+	   SANs: *.google.com, google.com
+	   Issuer: CN=WR2,O=Google Trust Services,C=US
+	 1 CN=WR2,O=Google Trust Services,C=US (expires 2029-02-20, 854 days remaining)
+	   Issuer: CN=GTS Root R1,O=Google Trust Services LLC,C=US
+
+Like curl, --resolve host:port:addr connects to addr instead of looking up host and --connect-to
+host1:port1:host2:port2 connects to host2:port2 for requests to host1:port1, where empty parts
+match any host or port and keep the original. The Host header and TLS server name stay those of the
+url. -4 and -6 only connect to IPv4 or IPv6 addresses.
+
+	$ ron hs --resolve www.example.com:443:10.0.3.12 https://www.example.com/health
+	$ ron hs --connect-to www.example.com:443:new-origin.example.net:443 https://www.example.com/
+
+--unix-socket connects to a unix socket instead of the url's host, which still sets the Host header.
+Requests use the proxy from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables unless
+--proxy is given. For https through a proxy the TCP connection is to the proxy and the CONNECT of
+the tunnel is timed separately from the TLS handshake with the origin.
+
+	$ ron hs --unix-socket /var/run/app.sock http://localhost/health
+	$ ron hs --proxy egress:3128 https://api.example.com/
+	Connected to 10.0.0.5:3128
+	Proxy http://egress:3128, CONNECT tunnel 41ms
+
+Assertions on the final response make hs usable as a smoke test in a target, it exits 1 with a
+message for each failure. --expect-status takes comma separated codes, --expect-header a header
+name which must be set, containing the value after a colon if given, and --expect-body-regex a
+regular expression. --max-dns, --max-connect, --max-tls, --max-server, --max-transfer and
+--max-total are budgets for each phase.
+
+	$ ron hs --expect-status 200 --expect-header 'Cache-Control: max-age' --max-total 500ms https://www.example.com/
+	...
+	2026/10/19 07:16:01 deploy1 status 503, expected 200; header Cache-Control missing; total took 612.3ms, over 500ms
+
+--compare requests two urls, alternately -n times each, and shows the final responses side by side
+with the median of each phase, the change from the first to the second and the headers whose values
+differ.
+
+	$ ron hs --compare -n 5 https://www.example.com/ https://canary.example.com/
+
+	Median of 5 runs each
+
+	                   A                         B                            DELTA
+	URL                https://www.example.com/  https://canary.example.com/
+	Remote Address     93.184.216.34:443         93.184.216.40:443
+	Status             200                       200
+	Protocol           HTTP/2.0                  HTTP/2.0
+	DNS Lookup         3.12ms                    3.40ms                       +0.28ms (+9%)
+	TCP Connection     11.02ms                   10.87ms                      -0.15ms (-1%)
+	TLS Handshake      24.51ms                   23.96ms                      -0.55ms (-2%)
+	Server Processing  48.20ms                   31.75ms                      -16.45ms (-34%)
+	Content Transfer   0.31ms                    0.29ms                       -0.02ms (-6%)
+	Total              87.40ms                   70.52ms                      -16.88ms (-19%)
+
+	Differing headers
+	Server   ECS (sec/96EC)  envoy
+	X-Cache  HIT             -
+
+The request options follow curl. -d sends a body with any method and makes the default method POST,
+a redirected POST is followed with a GET unless the status is 307 or 308. -u user:password uses
+basic authentication for the url's host, -b sends cookies as name=value pairs separated by ; or from
+a Netscape cookie file and -c writes the cookies to one, keeping them across redirects. With
+--compare both urls get the credentials and name=value cookies. --compressed asks for a gzip or
+deflate response and reports its size before and after decoding, -o file saves the final body,
+--http1.1 and --http2 force a protocol and -L=false stops at the first response. As -o text and
+-o json choose the output format and -c with a number is the concurrency of -n, -format, -cookie-jar
+and -concurrency can be used for files with those names. -o file and -c file can't be used with
+-n or --compare.
+
+	$ ron hs -c cookies.txt -d 'user=me&password=secret' https://www.example.com/login
+	$ ron hs -b cookies.txt --compressed -o page.html https://www.example.com/account
+	...
+	Body saved to page.html (48213 bytes, 9120 bytes gzip compressed)
+
+When redirects are followed a waterfall of the hops is written after the last response with when
+each started and finished, and with -o json the cumulative time of all of them is the elapsed field.
+Server-Timing headers break Server Processing down into the metrics the server reported, with the
+rest being the network and what the server didn't report.
+
+	$ ron hs http://example.com/
+	...
+	Server Processing 82ms, Server-Timing:
+	  db    53.00ms  65%  Database
+	  app   20.10ms  25%
+	  rest   8.90ms  11%  network and unreported
+
+	Redirects
+	  #  STATUS  URL                    START  TOTAL  CUMULATIVE
+	  1  301     http://example.com/    0ms    41ms   41ms        |=========                               |
+	  2  200     https://example.com/   41ms   145ms  186ms       |         ===============================|
+
+With -o json the timings in milliseconds, status, protocol, headers and remote address are written
+as one JSON document, with any redirects followed on the way listed under redirects.
+
+	$ ron hs -o json http://google.com | jq .timings.total
+	90.412
+
+With -n the request is repeated, -c at a time, and the distribution of each phase is reported along
+with the status codes and errors by kind. Connections are reused unless -reuse=false, so DNS, TCP
+and TLS are only counted for new connections. Redirects are not followed.
+
+	$ ron hs -n 100 -c 10 https://google.com
+
+	100 requests to https://google.com with concurrency 10, 90 reused connections, 0 errors
+
+	PHASE              COUNT  MIN      MEAN     P50      P90      P99      MAX
+	DNS Lookup         10     2.11ms   4.87ms   4.12ms   9.30ms   9.30ms   9.30ms
+	TCP Connection     10     11.20ms  12.04ms  11.87ms  13.95ms  13.95ms  13.95ms
+	TLS Handshake      10     24.61ms  27.33ms  26.90ms  31.02ms  31.02ms  31.02ms
+	Server Processing  100    38.40ms  45.12ms  43.75ms  52.66ms  71.30ms  74.08ms
+	Content Transfer   100    0.02ms   0.41ms   0.06ms   1.20ms   3.10ms   3.52ms
+	Total              100    38.51ms  50.86ms  44.30ms  88.20ms  95.71ms  97.64ms
+
+	Status codes: 301 x100
+
 Cmd
 
 The cmd (cmd) command allows for watching file changes and restarting or executing commands.
 
 	$ ron cmd
-	Usage: ron cmd --wait --watch <path> --restart <command>
-		-ignore string
-				a comma seperated list of regex patterns to ignore *optional (default ".*\\.git.*,.*\\.DS_Store$,.*\\.pyc$")
-		-restart
-				Restart the command if it dies.
-		-wait
-				With watch wait for file changes before running the command.
-		-watch string
-				Path to directory or file to watch.
-
-	$ ron cmd --restart ls
-	2015/08/03 17:02:38 runit.go:69: running ls
-	2015/08/03 17:02:38 runit.go:104: running ls
-	Dockerfile	README.md	_vendor		bin		config		make.sh		src
-	2015/08/03 17:02:38 cmd.go:40: captured child exited continue...
-	2015/08/03 17:02:39 runit.go:69: running ls
-	2015/08/03 17:02:39 runit.go:104: running ls
-	Dockerfile	README.md	_vendor		bin		config		make.sh		src
-	2015/08/03 17:02:39 cmd.go:40: captured child exited continue...
-	^C2015/08/03 17:02:40 cmd.go:37: captured interrupt
-
-	$ ron cmd --wait --watch . ls
-	2015/08/03 17:03:06 runit.go:104: running ls
-	Dockerfile	README.md	_vendor		bin		config		make.sh		src
-	2015/08/03 17:03:06 cmd.go:40: captured child exited continue...
-	2015/08/03 17:03:11 watch.go:28: event:  "foo": CREATE
-	2015/08/03 17:03:11 watch.go:47: Detected new file foo
-	2015/08/03 17:03:11 runit.go:87: restart event
-	2015/08/03 17:03:11 runit.go:141: restarting
-	2015/08/03 17:03:11 runit.go:123: killing subprocess
-	2015/08/03 17:03:11 runit.go:104: running ls
-	Dockerfile	README.md	_vendor		bin		config		foo		make.sh		src
-	2015/08/03 17:03:11 cmd.go:40: captured child exited continue...
-	2015/08/03 17:03:11 watch.go:28: event:  "foo": CHMOD
-	2015/08/03 17:03:11 watch.go:28: event:  "foo": CHMOD
+	Usage: ron cmd -watch <path> -wait -restart -include <globs> -ignore <globs> <command>
+	       ron cmd -procfile <path> | -processes [process...]
+	  -debounce duration
+	    	With watch how long to wait for changes to settle before restarting. (default 200ms)
+	  -gitignore
+	    	With watch ignore the files in the watched directory's .gitignore. (default true)
+	  -ignore string
+	    	With watch a comma separated list of globs of files and directories to ignore. (default ".DS_Store,*.pyc,*.swp,*~")
+	  -include string
+	    	With watch a comma separated list of globs of files which restart the command, all files by default.
+	  -processes
+	    	Run each process in the processes section of the ron.yaml config, or only the processes given as arguments.
+	  -procfile string
+	    	Run each process in a Procfile of name: command lines, or only the processes given as arguments.
+	  -quiet
+	    	Don't print messages when the command is started or restarted.
+	  -restart
+	    	Restart the command if it dies, backing off while it keeps failing.
+	  -wait
+	    	With watch wait for file changes before running the command.
+	  -watch string
+	    	Path to directory or file to watch.
+
+Directories below the watched path are added as they are created, a change is only acted on once
+no other change has happened for the debounce period and mode only changes are ignored. Globs
+without a slash match file names at any depth and ** matches any number of directories. A command
+which keeps exiting with an error soon after starting is restarted with an increasing delay up to 30s.
+
+	$ ron cmd -restart ls
+	running ls
+	Dockerfile	README.md	bin		config		make.sh		src
+	exited 0, restarting in 1s
+	running ls
+	Dockerfile	README.md	bin		config		make.sh		src
+	exited 0, restarting in 1s
+	^C
+
+	$ ron cmd -wait -watch . -include "*.go" go test ./...
+	changed foo.go, restarting
+	running go test ./...
+	ok  	example.com/foo	0.012s
+	exited 0, waiting for changes
+
+Several long running processes can be supervised together with -procfile, a file of "name: command"
+lines, or -processes which uses the processes section of the ron.yaml config. Each process has its
+output prefixed with its name, is restarted when it fails or when files matching its watch globs
+change, and all of them are stopped together on Ctrl-C.
+
+	processes:
+	  api: go run ./cmd/api
+	  worker:
+	    cmd: go run ./cmd/worker
+	    watch:
+	      - "*.go"
+	  web: npm run watch
+
+	$ ron cmd -processes api worker
+	api] running go run ./cmd/api
+	worker] running go run ./cmd/worker
+	api] listening on :8080
 
 Replace
 
@@ -139,12 +289,28 @@ target name seperated by colon.
 	    	Path to the default yaml config file, local or http.
 	  -envs
 	    	List the initialized environment variables.
+	  -grace duration
+	    	How long an interrupted target is given to exit before it is killed. (default 10s)
 	  -l	List the available targets.
+	  -last
+	    	Show the timing summary of the previous run.
 	  -list
 	    	List the available targets.
+	  -log_dir string
+	    	Directory to write a json lines log of each run to, empty disables the log. (default ".ron/logs")
+	  -log_keep int
+	    	The number of run logs to keep including this run's, older ones are removed. (default 20)
+	  -output string
+	    	How output from remote hosts is shown, "prefixed" writes lines as they arrive, "grouped" writes each hosts output once it finishes. (default "prefixed")
+	  -report value
+	    	Write a report once targets finish, "junit=path.xml" for JUnit XML or "github" for GitHub Actions annotations on stdout. Can be repeated.
 	  -v	Be verbose.
 	  -verbose
 	    	Be verbose.
+	  -watch
+	    	Run the targets again when files matching their watch globs, or any file when none are set, change.
+	  -watch_ignore string
+	    	Comma separated globs of files to ignore in watch mode, in addition to .gitignore.
 	  -yaml string
 	    	Path to override yaml file, can be local or http.
 
@@ -182,6 +348,23 @@ target name seperated by colon.
 		If no identity file is provided, the users local ssh agent will be attempted. You can add
 		keys with ssh-add.
 
+		Remote commands are only run with a pseudo terminal when ron is run interactively,
+		which keeps stdout and stderr separate when output is captured. Set pty: true or
+		pty: false on a remote or a target to override this, the target taking precedence.
+
+		Setting sudo or sudo_user on a remote or a target will run the target cmd through sudo,
+		the target settings taking precedence. Use -ask_sudo_pass to be prompted once for the
+		password used on every host, otherwise sudo must not require one.
+
+			remotes:
+				production:
+					-
+						host: exampleprod.com
+						port: 22
+						user: test
+						sudo: true
+						sudo_user: deploy
+
 		env values prefixed with a +(subject to change) will be executed and set to the os environment
 		prior to target execution.
 
@@ -205,6 +388,129 @@ target name seperated by colon.
 					cmd: |
 						echo $APP
 
+		A shell can be set for all targets in a file or on a single target, the default being
+		bash -e, or sh -e when bash isn't installed and inside containers. Shells and interpreters
+		such as sh, zsh, python3 or node are given the cmd inline, any other command is given it as
+		a script file argument.
+
+			shell: bash -euo pipefail
+			targets:
+				hello:
+					shell: python3
+					cmd: |
+						print("hello")
+
+		targets with an image run their cmd inside a container using the local container runtime,
+		docker unless RON_CONTAINER_RUNTIME is set. The current directory is mounted at the same
+		path and used as the working directory unless workdir is set. Only the envs declared in the
+		ron configs are passed into the container, the rest of the host environment is not. The cmd
+		is run with sh -e inside the container unless shell is set, as not every image has bash.
+
+			targets:
+				test:
+					image: golang:1.21
+					volumes:
+						- $HOME/go/pkg/mod:/go/pkg/mod
+					cmd: |
+						go test ./...
+
+		targets run with -remotes can upload local files before the cmd is run and
+		download remote files after it finishes. Upload src is a local glob and download
+		src is expanded by the remote shell. File modes are preserved.
+
+			targets:
+				deploy:
+					upload:
+						- src: bin/app-*
+						  dst: /opt/app/
+					cmd: |
+						/opt/app/install.sh
+					download:
+						- src: /var/log/app/install.log
+						  dst: logs/
+
+		With -watch targets are run again whenever a file matching the watch globs of the
+		target, or of its before and after targets, changes. Without any globs every file
+		is watched. Files in .gitignore, the .ron directory and the -log_dir directory are
+		ignored. Before the next run starts a run still in progress is cancelled, the process
+		group of its running cmd is sent SIGTERM and killed if it has not exited after the
+		-grace period, and its background targets are stopped and torn down.
+
+			targets:
+				test:
+					watch:
+						- "*.go"
+						- go.mod
+					cmd: |
+						go test ./...
+
+		wait_for runs readiness probes before the cmd, retrying every interval (default 1s) until they all
+		pass or the timeout (default 30s) is reached. tcp waits for a host:port to accept connections, http
+		for a url to respond with status or any 2xx or 3xx, file for a path to exist and cmd for a command
+		to exit 0. Probes are only run for local targets.
+
+			targets:
+				integration:
+					before:
+						- db
+					wait_for:
+						tcp: localhost:$DB_PORT
+						http: http://localhost:8080/health
+						status: 200
+						timeout: 1m
+						interval: 500ms
+					cmd: |
+						go test -tags integration ./...
+
+		background targets start their cmd without waiting for it to exit, keeping it running while the
+		targets that depend on it run. Once the top level run finishes or fails each background target has
+		its process group sent SIGTERM, anything left in the group once the cmd exits is killed, and its
+		teardown target, if set, is run. Output is prefixed with the target name and stdin is empty, leaving
+		the terminal to the other targets.
+
+			targets:
+				postgres:
+					background: true
+					teardown: postgres_clean
+					cmd: |
+						docker run --rm --name ron-pg -p 5432:5432 postgres:16
+				postgres_clean:
+					cmd: |
+						docker rm -f ron-pg || true
+				test:
+					before:
+						- postgres
+					wait_for:
+						tcp: localhost:5432
+					cmd: |
+						go test ./...
+
+Each target cmd runs in its own process group. On SIGINT or SIGTERM the signal is forwarded
+to the whole group, which is killed if it has not exited after the -grace period, so servers and
+other children started by a target are not left running. When stdin is a terminal the group is made
+the terminal's foreground group while the cmd runs, so it can read from the terminal and Ctrl-C
+reaches the whole group directly, and the terminal is given back to ron once it exits.
+
+Every run writes a json lines log to .ron/logs/<timestamp>.jsonl next to the found config, with
+an event for each target started, finished or skipped, its duration, exit status, host and the tail
+of its output. Output written directly to a terminal is left as is, so commands can still detect
+the terminal, and isn't in the log; redirect or pipe it to record it. The directory is created with
+a .gitignore of its own, only the last 20 logs are kept unless -log_keep is set and -log_dir= turns
+the log off. A timing table is written to stderr once the run finishes, and the previous run's
+table can be shown again with -last.
+
+	$ ron t -last
+	TARGET    HOST  STATUS      DURATION
+	go:prep         ok          1.2s
+	go:test         failed (1)  35.41s
+	total                       36.63s
+
+Reports of the run can be written with -report. junit=path.xml writes each finished target, and
+each remote host it ran on, as a JUnit test case with its duration, stdout, stderr and failure status.
+github writes a GitHub Actions error annotation to stdout for each failed target.
+
+	$ ron t -report junit=report.xml -report github test
+
 In order to execute a target you can either run it with the yaml file prefix without extension
 or if you leave that off it will find the first available target, with the default targets executing
 last.
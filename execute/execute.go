@@ -2,6 +2,7 @@
 package execute
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -9,6 +10,7 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/upsight/ron/color"
 )
@@ -16,19 +18,24 @@ import (
 var (
 	// Debug prints the command being run if set to true.
 	Debug = false
+
+	// GracePeriod is how long an interrupted process group is given to
+	// exit before it is killed.
+	GracePeriod = 10 * time.Second
 )
 
-// WaitNoop accepts and waits on any signal and returns on kill signals.
+// WaitNoop waits on kill signals and kills the process group of cmd
+// when one is received. Wait should be preferred as it allows the
+// process to exit gracefully.
 func WaitNoop(interrupt chan os.Signal, cmd *exec.Cmd) {
-	signal.Notify(interrupt)
+	signal.Notify(interrupt, forwardSignals...)
+	defer signal.Stop(interrupt)
 	for {
 		select {
 		case sig := <-interrupt:
 			switch sig {
 			case syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGKILL:
-				if cmd.Process != nil {
-					cmd.Process.Kill()
-				}
+				signalGroup(cmd, os.Kill)
 				return
 			default:
 				continue
@@ -37,7 +44,71 @@ func WaitNoop(interrupt chan os.Signal, cmd *exec.Cmd) {
 	}
 }
 
-func getCmd(cmdString string, stdOut io.Writer, stdErr io.Writer, envs map[string]string) *exec.Cmd {
+// Wait waits for the started cmd to exit, forwarding any SIGHUP, SIGINT,
+// SIGTERM or SIGQUIT received to its process group. If the process has
+// not exited GracePeriod after the first signal the group is killed, as is
+// anything left in the group once an interrupted process exits.
+func Wait(cmd *exec.Cmd) error {
+	return WaitContext(context.Background(), cmd)
+}
+
+// WaitContext is Wait which also sends SIGTERM to the process group when
+// ctx is done, returning the context error once the process exits.
+func WaitContext(ctx context.Context, cmd *exec.Cmd) error {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, forwardSignals...)
+	defer signal.Stop(interrupt)
+	return waitInterrupt(ctx, interrupt, cmd)
+}
+
+func waitInterrupt(ctx context.Context, interrupt chan os.Signal, cmd *exec.Cmd) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	cancelled := ctx.Done()
+	var kill <-chan time.Time
+	for {
+		select {
+		case err := <-done:
+			restoreForeground(cmd)
+			// the terminal's Ctrl-C goes straight to the foreground
+			// group, so a process killed by a signal was interrupted too.
+			if kill != nil || signaled(cmd) {
+				signalGroup(cmd, os.Kill)
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		case sig := <-interrupt:
+			signalGroup(cmd, sig)
+			if kill == nil {
+				kill = time.After(GracePeriod)
+			}
+		case <-cancelled:
+			cancelled = nil
+			signalGroup(cmd, syscall.SIGTERM)
+			if kill == nil {
+				kill = time.After(GracePeriod)
+			}
+		case <-kill:
+			signalGroup(cmd, os.Kill)
+		}
+	}
+}
+
+// signaled reports whether the exited cmd was terminated by a signal.
+func signaled(cmd *exec.Cmd) bool {
+	if cmd.ProcessState == nil {
+		return false
+	}
+	status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled()
+}
+
+// debugCmd prints the cmdString with any envs expanded.
+func debugCmd(cmdString string, envs map[string]string) {
 	if Debug {
 		switch {
 		case envs != nil:
@@ -56,8 +127,13 @@ func getCmd(cmdString string, stdOut io.Writer, stdErr io.Writer, envs map[strin
 			fmt.Println(color.Blue(os.ExpandEnv(cmdString)))
 		}
 	}
-	cmd := exec.Command("bash", "-e", "-c", cmdString)
+}
+
+// setCmdIO sets the io and environment of cmd and starts it in its
+// own process group.
+func setCmdIO(cmd *exec.Cmd, stdOut io.Writer, stdErr io.Writer, envs map[string]string) {
 	cmd.Stdin = os.Stdin
+	setProcessGroup(cmd)
 	cmd.Stdout = stdOut
 	cmd.Stderr = stdErr
 	if envs != nil {
@@ -66,25 +142,56 @@ func getCmd(cmdString string, stdOut io.Writer, stdErr io.Writer, envs map[strin
 			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
 		}
 	}
-	return cmd
+}
+
+// NoStdin gives the not yet started cmd no stdin, for commands run in the
+// background which mustn't compete for the terminal with those run in the
+// foreground.
+func NoStdin(cmd *exec.Cmd) {
+	cmd.Stdin = nil
+	setProcessGroup(cmd)
 }
 
 // Command just executes a given cmd string to the supplied io.Writer writers.
 // If optional envs is passed in then the expanded values will be used vs the os versions.
 func Command(cmdString string, stdOut io.Writer, stdErr io.Writer, envs map[string]string) (int, error) {
-	cmd := getCmd(cmdString, stdOut, stdErr, envs)
+	return CommandContext(context.Background(), cmdString, stdOut, stdErr, envs)
+}
+
+// CommandContext is Command which terminates the command when ctx is done.
+func CommandContext(ctx context.Context, cmdString string, stdOut io.Writer, stdErr io.Writer, envs map[string]string) (int, error) {
+	cmd, err := CommandNoWaitContext(ctx, cmdString, stdOut, stdErr, envs)
+	if cmd != nil {
+		defer Cleanup(cmd)
+	}
+	if err != nil {
+		return 1, err
+	}
 	exitStatus := 0
-	err := cmd.Run()
+	err = WaitContext(ctx, cmd)
 	if err != nil {
 		exitStatus = GetExitStatus(err)
 	}
 	return exitStatus, err
 }
 
-// CommandNoWait starts the given command but does not wait for it to finish. It returns
-// the created exec.Command which can be used with Wait.
+// CommandNoWait starts the given command in its own process group but does not wait for
+// it to finish. It returns the created exec.Command which can be used with Wait followed
+// by Cleanup.
 func CommandNoWait(cmdString string, stdOut io.Writer, stdErr io.Writer, envs map[string]string) (*exec.Cmd, error) {
-	cmd := getCmd(cmdString, stdOut, stdErr, envs)
+	return CommandNoWaitContext(context.Background(), cmdString, stdOut, stdErr, envs)
+}
+
+// CommandNoWaitContext is CommandNoWait which does not start the command if ctx
+// is already done. Use WaitContext to terminate the command when ctx is done.
+func CommandNoWaitContext(ctx context.Context, cmdString string, stdOut io.Writer, stdErr io.Writer, envs map[string]string) (*exec.Cmd, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cmd, err := Local{}.Cmd(cmdString, stdOut, stdErr, envs)
+	if err != nil {
+		return nil, err
+	}
 	return cmd, cmd.Start()
 }
 
@@ -2,6 +2,7 @@ package execute
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -135,3 +136,62 @@ func TestExecuteWaitNoopKill(t *testing.T) {
 	interrupt <- syscall.SIGINT
 	cmd.Wait()
 }
+
+func TestExecuteShellQuote(t *testing.T) {
+	var outBuf bytes.Buffer
+	var errBuf bytes.Buffer
+	in := `it's "$HOME" $(echo x)`
+	status, err := Command("printf '%s' "+ShellQuote(in), &outBuf, &errBuf, nil)
+	if status != 0 || err != nil {
+		t.Fatal(status, err, errBuf.String())
+	}
+	if outBuf.String() != in {
+		t.Errorf("want %q got %q", in, outBuf.String())
+	}
+}
+
+func TestExecuteSudoCommand(t *testing.T) {
+	tests := []struct {
+		cmd, user    string
+		withPassword bool
+		want         string
+	}{
+		{"whoami", "", false, `sudo -n -- sh -c 'whoami'`},
+		{"whoami", "deploy", false, `sudo -n -u 'deploy' -- sh -c 'whoami'`},
+	}
+	for _, tt := range tests {
+		got := SudoCommand(tt.cmd, tt.user, tt.withPassword)
+		if got != tt.want {
+			t.Errorf("want %q got %q", tt.want, got)
+		}
+	}
+	got := SudoCommand("whoami", "", true)
+	if !strings.HasPrefix(got, "IFS= read -r RON_SUDO_PASSWORD;") || !strings.HasSuffix(got, `sudo -n -- sh -c 'whoami'`) {
+		t.Errorf("unexpected password command %q", got)
+	}
+}
+
+func TestExecuteCommandContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	status, err := CommandContext(ctx, "sleep 30", ioutil.Discard, ioutil.Discard, nil)
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded got %v", err)
+	}
+	if status == 0 {
+		t.Error("expected non 0 status")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("command was not cancelled, took %s", time.Since(start))
+	}
+}
+
+func TestExecuteCommandNoWaitContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := CommandNoWaitContext(ctx, "echo nope", ioutil.Discard, ioutil.Discard, nil)
+	if err != context.Canceled {
+		t.Errorf("expected canceled got %v", err)
+	}
+}
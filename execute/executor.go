@@ -0,0 +1,140 @@
+package execute
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+)
+
+var (
+	// ContainerRuntime is the container runtime cli used by Container when
+	// its Runtime is not set. It can be overridden with RON_CONTAINER_RUNTIME.
+	ContainerRuntime = "docker"
+
+	// containerSkipEnvs are host specific environment variables that are
+	// not passed through to containers even when listed in Envs.
+	containerSkipEnvs = map[string]struct{}{
+		"HOME":     struct{}{},
+		"HOSTNAME": struct{}{},
+		"OLDPWD":   struct{}{},
+		"PATH":     struct{}{},
+		"PWD":      struct{}{},
+		"SHELL":    struct{}{},
+		"TMPDIR":   struct{}{},
+	}
+)
+
+// Executor creates the command which runs a cmd string. Cleanup should
+// be called on the returned command once it has finished.
+type Executor interface {
+	Cmd(cmdString string, stdOut io.Writer, stdErr io.Writer, envs map[string]string) (*exec.Cmd, error)
+}
+
+// Local runs commands with a shell on the local host.
+type Local struct {
+	Shell Shell // the shell to run commands with, defaults to DefaultShell.
+}
+
+// Cmd returns a shell command for cmdString.
+func (l Local) Cmd(cmdString string, stdOut io.Writer, stdErr io.Writer, envs map[string]string) (*exec.Cmd, error) {
+	debugCmd(cmdString, envs)
+	args, err := localShell(l.Shell).Args(cmdString)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	setCmdIO(cmd, stdOut, stdErr, envs)
+	return cmd, nil
+}
+
+// Container runs commands with a shell inside a container image using
+// the local container runtime cli. The current directory is mounted at
+// the same path in the container and used as the working directory
+// unless Workdir is set. Only the envs named in Envs are passed into the
+// container so the host environment doesn't leak into the image.
+type Container struct {
+	Runtime string   // the container runtime cli, defaults to ContainerRuntime.
+	Image   string   // the image to run.
+	Volumes []string // additional volumes in host:container[:options] format.
+	Workdir string   // the working directory inside the container.
+	Shell   Shell    // the shell inside the image, defaults to DefaultContainerShell.
+	Envs    []string // the names of the envs passed into the container.
+}
+
+// Cmd returns a container runtime command which runs cmdString in the
+// image. The envs named in Envs are passed through by name so their
+// values do not appear in the process list.
+func (c *Container) Cmd(cmdString string, stdOut io.Writer, stdErr io.Writer, envs map[string]string) (*exec.Cmd, error) {
+	debugCmd(cmdString, envs)
+	args, err := c.Args(cmdString, envs)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(c.runtime(), args...)
+	setCmdIO(cmd, stdOut, stdErr, envs)
+	return cmd, nil
+}
+
+// Args returns the container runtime arguments to run cmdString. Any
+// script file needed by the shell is mounted read only.
+func (c *Container) Args(cmdString string, envs map[string]string) ([]string, error) {
+	shell := c.Shell
+	if len(shell) == 0 {
+		shell = DefaultContainerShell
+	}
+	shellArgs, err := shell.Args(cmdString)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"run", "--rm", "-i"}
+	workdir := c.Workdir
+	if wd, err := os.Getwd(); err == nil {
+		args = append(args, "-v", wd+":"+wd)
+		if workdir == "" {
+			workdir = wd
+		}
+	}
+	for _, v := range c.Volumes {
+		args = append(args, "-v", v)
+	}
+	if script := scriptFile(&exec.Cmd{Args: shellArgs}); script != "" {
+		args = append(args, "-v", script+":"+script+":ro")
+	}
+	if workdir != "" {
+		args = append(args, "-w", workdir)
+	}
+	keys := []string{}
+	for _, k := range c.Envs {
+		_, ok := envs[k]
+		if _, skip := containerSkipEnvs[k]; ok && !skip && !keyIn(k, keys) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "-e", k)
+	}
+	args = append(args, c.Image)
+	return append(args, shellArgs...), nil
+}
+
+func keyIn(key string, keys []string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// runtime returns the container runtime cli to use.
+func (c *Container) runtime() string {
+	switch {
+	case c.Runtime != "":
+		return c.Runtime
+	case os.Getenv("RON_CONTAINER_RUNTIME") != "":
+		return os.Getenv("RON_CONTAINER_RUNTIME")
+	}
+	return ContainerRuntime
+}
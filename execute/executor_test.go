@@ -0,0 +1,106 @@
+package execute
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeRuntime creates a container runtime script on PATH which prints its
+// arguments and the RON_TEST env.
+func fakeRuntime(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "ronruntime")
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\necho \"$@\"\necho \"RON_TEST=$RON_TEST\"\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "fakedocker"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	prevPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+prevPath)
+	return func() {
+		os.Setenv("PATH", prevPath)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestExecutorLocal(t *testing.T) {
+	var outBuf bytes.Buffer
+	cmd, err := Local{}.Cmd("echo $RON_TEST", &outBuf, nil, map[string]string{"RON_TEST": "local"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if outBuf.String() != "local\n" {
+		t.Errorf("want local got %q", outBuf.String())
+	}
+}
+
+func TestExecutorContainer(t *testing.T) {
+	defer fakeRuntime(t)()
+	wd, _ := os.Getwd()
+
+	var outBuf bytes.Buffer
+	c := &Container{Runtime: "fakedocker", Image: "golang:1.21", Volumes: []string{"/cache:/cache"}, Envs: []string{"RON_TEST", "HOME", "MISSING"}}
+	envs := map[string]string{"RON_TEST": "container", "HOME": "/home/ron", "PATH": os.Getenv("PATH"), "GOPATH": "/home/ron/go"}
+	cmd, err := c.Cmd("go test", &outBuf, nil, envs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := "run --rm -i -v " + wd + ":" + wd + " -v /cache:/cache -w " + wd + " -e RON_TEST golang:1.21 sh -e -c go test\nRON_TEST=container\n"
+	if outBuf.String() != want {
+		t.Errorf("want %q got %q", want, outBuf.String())
+	}
+}
+
+func TestExecutorContainerWorkdir(t *testing.T) {
+	c := &Container{Image: "alpine", Workdir: "/src"}
+	a, err := c.Args("ls", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := strings.Join(a, " ")
+	if !strings.Contains(args, "-w /src alpine") || strings.Count(args, "-w ") != 1 {
+		t.Errorf("expected single workdir /src got %q", args)
+	}
+}
+
+func TestExecutorContainerRuntimeEnv(t *testing.T) {
+	os.Setenv("RON_CONTAINER_RUNTIME", "podman")
+	defer os.Unsetenv("RON_CONTAINER_RUNTIME")
+	c := &Container{Image: "alpine"}
+	if c.runtime() != "podman" {
+		t.Errorf("want podman got %s", c.runtime())
+	}
+	c.Runtime = "nerdctl"
+	if c.runtime() != "nerdctl" {
+		t.Errorf("want nerdctl got %s", c.runtime())
+	}
+}
+
+func TestExecutorContainerScriptFile(t *testing.T) {
+	c := &Container{Image: "alpine", Shell: Shell{"custom-interpreter"}}
+	args, err := c.Args("print 1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("fakedocker", args...)
+	script := scriptFile(cmd)
+	if script == "" {
+		t.Fatalf("expected script file in %v", args)
+	}
+	defer Cleanup(cmd)
+	if !strings.Contains(strings.Join(args, " "), "-v "+script+":"+script+":ro") {
+		t.Errorf("expected script mounted got %v", args)
+	}
+}
@@ -0,0 +1,107 @@
+package execute
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/upsight/ron/color"
+)
+
+// Output modes used when several commands write at once.
+const (
+	// OutputPrefixed writes each line as soon as it is complete.
+	OutputPrefixed = "prefixed"
+	// OutputGrouped buffers each writer's output until it is closed.
+	OutputGrouped = "grouped"
+)
+
+// maxLineLength is the longest partial line buffered before it is
+// written without waiting for a newline.
+const maxLineLength = 1 << 20
+
+// Mux writes the output of several named writers to one underlying
+// writer, only ever writing whole lines so output does not interleave.
+type Mux struct {
+	W       io.Writer
+	Grouped bool // buffer each writer's lines until it is closed.
+	mu      sync.Mutex
+}
+
+// NewMux creates a Mux writing to w with the given output mode.
+func NewMux(w io.Writer, output string) *Mux {
+	return &Mux{W: w, Grouped: output == OutputGrouped}
+}
+
+// Writer returns a writer which prefixes each line with name in a
+// stable color. It must be closed to write any remaining output.
+func (m *Mux) Writer(name string) *PrefixWriter {
+	return &PrefixWriter{
+		mux:    m,
+		prefix: []byte(color.Stable(name, name+"]") + " "),
+	}
+}
+
+func (m *Mux) write(b []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err := m.W.Write(b)
+	return err
+}
+
+// PrefixWriter is a line buffered writer created by Mux.Writer.
+type PrefixWriter struct {
+	mux    *Mux
+	prefix []byte
+	line   []byte       // the current partial line
+	block  bytes.Buffer // prefixed lines waiting to be written
+	mu     sync.Mutex
+}
+
+// Write buffers b, writing any complete lines with the prefix.
+func (p *PrefixWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.line = append(p.line, b...)
+	start := 0
+	for start < len(p.line) {
+		end, next := 0, 0
+		if i := bytes.IndexByte(p.line[start:], '\n'); i >= 0 {
+			end, next = start+i, start+i+1
+		} else if len(p.line)-start >= maxLineLength {
+			end, next = len(p.line), len(p.line)
+		} else {
+			break
+		}
+		p.block.Write(p.prefix)
+		p.block.Write(bytes.TrimSuffix(p.line[start:end], []byte("\r")))
+		p.block.WriteByte('\n')
+		start = next
+	}
+	p.line = append(p.line[:0], p.line[start:]...)
+	if p.mux.Grouped || p.block.Len() == 0 {
+		return len(b), nil
+	}
+	err := p.mux.write(p.block.Bytes())
+	p.block.Reset()
+	return len(b), err
+}
+
+// Close writes any partial line followed by a newline along with any
+// grouped output.
+func (p *PrefixWriter) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.line) > 0 {
+		p.block.Write(p.prefix)
+		p.block.Write(p.line)
+		p.block.WriteByte('\n')
+		p.line = nil
+	}
+	if p.block.Len() == 0 {
+		return nil
+	}
+	err := p.mux.write(p.block.Bytes())
+	p.block.Reset()
+	return err
+}
@@ -0,0 +1,94 @@
+package execute
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/upsight/ron/color"
+)
+
+func TestMuxPartialWrites(t *testing.T) {
+	out := &bytes.Buffer{}
+	w := NewMux(out, OutputPrefixed).Writer("host1")
+	prefix := color.Stable("host1", "host1]") + " "
+	w.Write([]byte("hel"))
+	if out.Len() != 0 {
+		t.Fatalf("expected partial line to be buffered got %q", out.String())
+	}
+	w.Write([]byte("lo\r\nwor"))
+	want := prefix + "hello\n"
+	if out.String() != want {
+		t.Errorf("want %q got %q", want, out.String())
+	}
+	w.Write([]byte("ld"))
+	w.Close()
+	want += prefix + "world\n"
+	if out.String() != want {
+		t.Errorf("want %q got %q", want, out.String())
+	}
+}
+
+func TestMuxLongLine(t *testing.T) {
+	out := &bytes.Buffer{}
+	w := NewMux(out, OutputPrefixed).Writer("host1")
+	long := strings.Repeat("a", 100*1024)
+	fmt.Fprintln(w, long)
+	w.Close()
+	if !strings.Contains(out.String(), long+"\n") {
+		t.Errorf("long line was not written whole, got %d bytes", out.Len())
+	}
+}
+
+func TestMuxGrouped(t *testing.T) {
+	out := &bytes.Buffer{}
+	m := NewMux(out, OutputGrouped)
+	a, b := m.Writer("a"), m.Writer("b")
+	fmt.Fprintln(a, "a1")
+	fmt.Fprintln(b, "b1")
+	fmt.Fprintln(a, "a2")
+	if out.Len() != 0 {
+		t.Fatalf("expected grouped output to be buffered got %q", out.String())
+	}
+	b.Close()
+	a.Close()
+	prefixA, prefixB := color.Stable("a", "a]")+" ", color.Stable("b", "b]")+" "
+	want := prefixB + "b1\n" + prefixA + "a1\n" + prefixA + "a2\n"
+	if out.String() != want {
+		t.Errorf("want %q got %q", want, out.String())
+	}
+}
+
+func TestMuxConcurrentWriters(t *testing.T) {
+	out := &bytes.Buffer{}
+	m := NewMux(out, OutputPrefixed)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			w := m.Writer(name)
+			defer w.Close()
+			for j := 0; j < 100; j++ {
+				// write each line in two parts to force buffering.
+				w.Write([]byte(name + "-"))
+				w.Write([]byte(name + "\n"))
+			}
+		}(fmt.Sprintf("host%d", i))
+	}
+	wg.Wait()
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 400 {
+		t.Fatalf("expected 400 lines got %d", len(lines))
+	}
+	for _, line := range lines {
+		for i := 0; i < 4; i++ {
+			name := fmt.Sprintf("host%d", i)
+			if strings.HasPrefix(line, color.Stable(name, name+"]")) && !strings.HasSuffix(line, " "+name+"-"+name) {
+				t.Fatalf("interleaved line %q", line)
+			}
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package execute
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Transfer is a file copy between the local host and a remote host.
+// For uploads Src is a local glob pattern and Dst the remote path, for
+// downloads Src is the remote path and Dst the local path.
+type Transfer struct {
+	Src string `json:"src" yaml:"src"`
+	Dst string `json:"dst" yaml:"dst"`
+}
+
+// Upload copies the local files matching the glob pattern src to the
+// remote path dst using the scp protocol. If more than one file matches,
+// dst should be an existing remote directory. File modes are preserved.
+func (s *SSH) Upload(src, dst string) error {
+	files, err := filepath.Glob(src)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("upload %s: no matching files", src)
+	}
+	if s.client == nil {
+		if err := s.Connect(); err != nil {
+			return err
+		}
+		defer s.Close()
+	}
+
+	session, err := s.client.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to create session: %s", err)
+	}
+	defer session.Close()
+	w, err := session.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("unable to setup stdin for session: %v", err)
+	}
+	r, err := session.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("unable to setup stdout for session: %v", err)
+	}
+	if err := session.Start(scpSink(dst)); err != nil {
+		return err
+	}
+	err = scpSend(w, r, files, s.progress("upload", dst))
+	w.Close()
+	if werr := session.Wait(); err == nil {
+		err = werr
+	}
+	return err
+}
+
+// scpSink is the remote command receiving an upload to dst, which is
+// quoted so it is a single path to the remote shell.
+func scpSink(dst string) string {
+	return "scp -t " + ShellQuote(dst)
+}
+
+// Download copies the remote path src, which may contain glob patterns
+// expanded by the remote shell, to the local path dst using the scp
+// protocol. If dst is an existing directory or ends with a path separator
+// the files are written inside it. File modes are preserved.
+func (s *SSH) Download(src, dst string) error {
+	if s.client == nil {
+		if err := s.Connect(); err != nil {
+			return err
+		}
+		defer s.Close()
+	}
+
+	session, err := s.client.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to create session: %s", err)
+	}
+	defer session.Close()
+	w, err := session.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("unable to setup stdin for session: %v", err)
+	}
+	r, err := session.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("unable to setup stdout for session: %v", err)
+	}
+	if err := session.Start("scp -f " + src); err != nil {
+		return err
+	}
+	err = scpReceive(w, r, dst, s.progress("download", dst))
+	w.Close()
+	if werr := session.Wait(); err == nil {
+		err = werr
+	}
+	return err
+}
+
+// progress returns a callback which prints transfer progress in 25%
+// increments.
+func (s *SSH) progress(direction, dst string) func(name string, n, size int64) {
+	var last int64 = -1
+	return func(name string, n, size int64) {
+		if s.Stdout == nil {
+			return
+		}
+		pct := int64(100)
+		if size > 0 {
+			pct = n * 100 / size
+		}
+		if n == 0 {
+			last = -1
+		}
+		if pct/25 == last {
+			return
+		}
+		last = pct / 25
+		fmt.Fprintf(s.Stdout, "%s %s -> %s %d/%d bytes (%d%%)\n", direction, name, dst, n, size, pct)
+	}
+}
+
+// scpAck reads a single scp response, returning an error if the remote
+// reported one.
+func scpAck(r *bufio.Reader) error {
+	b, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if b == 0 {
+		return nil
+	}
+	msg, _ := r.ReadString('\n')
+	return fmt.Errorf("scp: %s", strings.TrimSpace(msg))
+}
+
+// progressWriter reports the number of bytes written so far.
+type progressWriter struct {
+	name     string
+	n, size  int64
+	progress func(name string, n, size int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	p.n += int64(len(b))
+	p.progress(p.name, p.n, p.size)
+	return len(b), nil
+}
+
+// scpSend implements the source side of the scp protocol, sending each
+// local file to the sink reading from w and replying on r.
+func scpSend(w io.Writer, r io.Reader, files []string, progress func(name string, n, size int64)) error {
+	br := bufio.NewReader(r)
+	if err := scpAck(br); err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := scpSendFile(w, br, file, progress); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scpSendFile(w io.Writer, br *bufio.Reader, file string, progress func(name string, n, size int64)) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("upload %s: directories are not supported", file)
+	}
+	name := filepath.Base(file)
+	if _, err := fmt.Fprintf(w, "C%04o %d %s\n", info.Mode().Perm(), info.Size(), name); err != nil {
+		return err
+	}
+	if err := scpAck(br); err != nil {
+		return err
+	}
+	progress(file, 0, info.Size())
+	pw := &progressWriter{name: file, size: info.Size(), progress: progress}
+	if _, err := io.Copy(io.MultiWriter(w, pw), f); err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte{0}); err != nil {
+		return err
+	}
+	return scpAck(br)
+}
+
+// scpReceive implements the sink side of the scp protocol, writing each
+// file sent by the source on r to dst and replying on w.
+func scpReceive(w io.Writer, r io.Reader, dst string, progress func(name string, n, size int64)) error {
+	br := bufio.NewReader(r)
+	toDir := strings.HasSuffix(dst, string(os.PathSeparator))
+	if info, err := os.Stat(dst); err == nil && info.IsDir() {
+		toDir = true
+	}
+	if _, err := w.Write([]byte{0}); err != nil {
+		return err
+	}
+	received := 0
+	for {
+		line, err := br.ReadString('\n')
+		if err == io.EOF && line == "" {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch line[0] {
+		case 1, 2:
+			return fmt.Errorf("scp: %s", strings.TrimSpace(line[1:]))
+		case 'T':
+			// modification times, only sent when requested with -p.
+			if _, err := w.Write([]byte{0}); err != nil {
+				return err
+			}
+			continue
+		case 'C':
+		default:
+			return fmt.Errorf("scp: unsupported message %q", strings.TrimSpace(line))
+		}
+		fields := strings.SplitN(strings.TrimSpace(line[1:]), " ", 3)
+		if len(fields) != 3 {
+			return fmt.Errorf("scp: invalid file header %q", strings.TrimSpace(line))
+		}
+		mode, err := strconv.ParseUint(fields[0], 8, 32)
+		if err != nil {
+			return fmt.Errorf("scp: invalid file mode %q", fields[0])
+		}
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("scp: invalid file size %q", fields[1])
+		}
+		path := dst
+		if toDir {
+			path = filepath.Join(dst, filepath.Base(fields[2]))
+		} else if received > 0 {
+			return fmt.Errorf("download to %s: multiple files require a directory", dst)
+		}
+		if err := scpReceiveFile(w, br, path, os.FileMode(mode), size, progress); err != nil {
+			return err
+		}
+		received++
+	}
+	if received == 0 {
+		return fmt.Errorf("download to %s: no files received", dst)
+	}
+	return nil
+}
+
+func scpReceiveFile(w io.Writer, br *bufio.Reader, path string, mode os.FileMode, size int64, progress func(name string, n, size int64)) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := w.Write([]byte{0}); err != nil {
+		return err
+	}
+	progress(path, 0, size)
+	pw := &progressWriter{name: path, size: size, progress: progress}
+	if _, err := io.CopyN(io.MultiWriter(f, pw), br, size); err != nil {
+		return err
+	}
+	if err := scpAck(br); err != nil {
+		return err
+	}
+	// OpenFile only applies the mode on creation and is subject to umask.
+	if err := f.Chmod(mode); err != nil {
+		return err
+	}
+	_, err = w.Write([]byte{0})
+	return err
+}
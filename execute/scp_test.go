@@ -0,0 +1,141 @@
+package execute
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func noProgress(name string, n, size int64) {}
+
+func TestScpSend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ronscp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "a.sh")
+	if err := ioutil.WriteFile(file, []byte("echo hi\n"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	os.Chmod(file, 0750)
+
+	// the sink acks the start, the header, and the file content.
+	acks := bytes.NewReader([]byte{0, 0, 0})
+	out := &bytes.Buffer{}
+	if err := scpSend(out, acks, []string{file}, noProgress); err != nil {
+		t.Fatal(err)
+	}
+	want := "C0750 8 a.sh\necho hi\n\x00"
+	if out.String() != want {
+		t.Errorf("want %q got %q", want, out.String())
+	}
+}
+
+func TestScpSendRemoteErr(t *testing.T) {
+	acks := bytes.NewReader([]byte("\x01scp: /nope: No such file or directory\n"))
+	err := scpSend(ioutil.Discard, acks, []string{"scp_test.go"}, noProgress)
+	if err == nil || err.Error() != "scp: scp: /nope: No such file or directory" {
+		t.Fatalf("expected remote error got %v", err)
+	}
+}
+
+func TestScpReceive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ronscp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := "C0700 5 one\nhello\x00C0644 3 two\nbye\x00"
+	acks := &bytes.Buffer{}
+	if err := scpReceive(acks, bytes.NewReader([]byte(source)), dir, noProgress); err != nil {
+		t.Fatal(err)
+	}
+	if acks.Len() != 5 {
+		t.Errorf("expected 5 acks got %d", acks.Len())
+	}
+	for name, want := range map[string]struct {
+		content string
+		mode    os.FileMode
+	}{"one": {"hello", 0700}, "two": {"bye", 0644}} {
+		path := filepath.Join(dir, name)
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want.content {
+			t.Errorf("%s want %q got %q", name, want.content, string(b))
+		}
+		info, _ := os.Stat(path)
+		if info.Mode().Perm() != want.mode {
+			t.Errorf("%s want mode %o got %o", name, want.mode, info.Mode().Perm())
+		}
+	}
+}
+
+func TestScpReceiveMultipleToFileErr(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ronscp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := "C0644 1 one\na\x00C0644 1 two\nb\x00"
+	err = scpReceive(ioutil.Discard, bytes.NewReader([]byte(source)), filepath.Join(dir, "file"), noProgress)
+	if err == nil {
+		t.Fatal("expected multiple files error")
+	}
+}
+
+func TestScpReceiveRemoteErr(t *testing.T) {
+	r := bytes.NewReader([]byte("\x01scp: nope: No such file or directory\n"))
+	err := scpReceive(ioutil.Discard, r, os.TempDir(), noProgress)
+	if err == nil || !strings.Contains(err.Error(), "No such file") {
+		t.Fatalf("expected remote error got %v", err)
+	}
+}
+
+func TestSSHProgress(t *testing.T) {
+	out := &bytes.Buffer{}
+	s, _ := NewSSH(&SSHConfig{Host: "example.com"}, nil, out, nil)
+	p := s.progress("upload", "/tmp")
+	p("a", 0, 100)
+	p("a", 10, 100)
+	p("a", 50, 100)
+	p("a", 100, 100)
+	lines := bytes.Count(out.Bytes(), []byte("\n"))
+	if lines != 3 {
+		t.Errorf("expected 3 progress lines got %d %q", lines, out.String())
+	}
+}
+
+func TestScpSinkQuotesPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ronscp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// a fake scp printing each of its arguments on a line.
+	fake := "#!/bin/sh\nfor a in \"$@\"; do echo \"$a\"; done\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "scp"), []byte(fake), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, dst := range []string{"/tmp/my dir", "/tmp/a; touch pwned", "/tmp/$(touch pwned)", "/tmp/it's"} {
+		var outBuf bytes.Buffer
+		var errBuf bytes.Buffer
+		status, err := Command("cd "+ShellQuote(dir)+" && PATH=.:$PATH "+scpSink(dst), &outBuf, &errBuf, nil)
+		if status != 0 || err != nil {
+			t.Fatal(status, err, errBuf.String())
+		}
+		if want := "-t\n" + dst + "\n"; outBuf.String() != want {
+			t.Errorf("want %q got %q", want, outBuf.String())
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pwned")); err == nil {
+		t.Error("expected the path not to run commands")
+	}
+}
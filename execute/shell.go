@@ -0,0 +1,119 @@
+package execute
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const scriptPrefix = "ron-script-"
+
+var (
+	// DefaultShell is used to run commands when no shell is configured.
+	// Locally it falls back to sh when bash is not installed.
+	DefaultShell = Shell{"bash", "-e"}
+
+	// DefaultContainerShell is used inside containers when no shell is
+	// configured, as images such as alpine don't include bash.
+	DefaultContainerShell = Shell{"sh", "-e"}
+
+	// shellFlags maps known interpreters to the flag used to pass the
+	// script inline. Any other interpreter is given a script file.
+	shellFlags = map[string]string{
+		"ash":     "-c",
+		"bash":    "-c",
+		"dash":    "-c",
+		"fish":    "-c",
+		"ksh":     "-c",
+		"sh":      "-c",
+		"zsh":     "-c",
+		"python":  "-c",
+		"python2": "-c",
+		"python3": "-c",
+		"node":    "-e",
+		"perl":    "-e",
+		"ruby":    "-e",
+	}
+)
+
+// Shell is the interpreter and its arguments used to run a script, such
+// as "bash -euo pipefail" or "python3". In yaml it can be given as a
+// string or a list.
+type Shell []string
+
+// UnmarshalYAML accepts either a space separated string or a list.
+func (s *Shell) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var str string
+	if err := unmarshal(&str); err == nil {
+		*s = strings.Fields(str)
+		return nil
+	}
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+// String returns the shell arguments space separated.
+func (s Shell) String() string {
+	return strings.Join(s, " ")
+}
+
+// Args returns the command line to run script. Known shells and
+// interpreters are given the script inline, otherwise it is written to a
+// temporary file appended to the arguments which should be removed with
+// Cleanup once the command finishes.
+func (s Shell) Args(script string) ([]string, error) {
+	if len(s) == 0 {
+		s = DefaultShell
+	}
+	args := append([]string{}, s...)
+	if flag, ok := shellFlags[filepath.Base(args[0])]; ok {
+		return append(args, flag, script), nil
+	}
+	f, err := ioutil.TempFile("", scriptPrefix)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := f.WriteString(script); err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return append(args, f.Name()), nil
+}
+
+// localShell returns s, or the default shell available on this host.
+func localShell(s Shell) Shell {
+	if len(s) > 0 {
+		return s
+	}
+	if _, err := exec.LookPath(DefaultShell[0]); err != nil {
+		return Shell{"sh", "-e"}
+	}
+	return DefaultShell
+}
+
+// scriptFile returns the temporary script file used by cmd if any.
+func scriptFile(cmd *exec.Cmd) string {
+	if len(cmd.Args) < 2 {
+		return ""
+	}
+	path := cmd.Args[len(cmd.Args)-1]
+	if filepath.Dir(path) != filepath.Clean(os.TempDir()) || !strings.HasPrefix(filepath.Base(path), scriptPrefix) {
+		return ""
+	}
+	return path
+}
+
+// Cleanup removes any temporary script file created to run cmd. It
+// should be called after the command has finished.
+func Cleanup(cmd *exec.Cmd) {
+	if path := scriptFile(cmd); path != "" {
+		os.Remove(path)
+	}
+}
@@ -0,0 +1,99 @@
+package execute
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestShellUnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Shell
+	}{
+		{`shell: bash -euo pipefail`, Shell{"bash", "-euo", "pipefail"}},
+		{`shell: [python3, -u]`, Shell{"python3", "-u"}},
+		{`other: true`, nil},
+	}
+	for _, tt := range tests {
+		var c struct {
+			Shell Shell `yaml:"shell"`
+		}
+		if err := yaml.Unmarshal([]byte(tt.in), &c); err != nil {
+			t.Fatal(err)
+		}
+		if c.Shell.String() != tt.want.String() {
+			t.Errorf("want %q got %q", tt.want, c.Shell)
+		}
+	}
+}
+
+func TestShellArgs(t *testing.T) {
+	tests := []struct {
+		shell Shell
+		want  string
+	}{
+		{nil, "bash -e -c echo"},
+		{Shell{"sh"}, "sh -c echo"},
+		{Shell{"bash", "-euo", "pipefail"}, "bash -euo pipefail -c echo"},
+		{Shell{"/usr/bin/python3"}, "/usr/bin/python3 -c echo"},
+		{Shell{"node"}, "node -e echo"},
+	}
+	for _, tt := range tests {
+		args, err := tt.shell.Args("echo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(args, " "); got != tt.want {
+			t.Errorf("want %q got %q", tt.want, got)
+		}
+	}
+}
+
+func TestShellArgsScriptFile(t *testing.T) {
+	args, err := Shell{"cat"}.Args("print('hi')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	script := scriptFile(cmd)
+	if script == "" {
+		t.Fatalf("expected a script file got %v", args)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "print('hi')" {
+		t.Errorf("unexpected script content %q", out)
+	}
+	Cleanup(cmd)
+	if _, err := os.Stat(script); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed", script)
+	}
+}
+
+func TestShellLocalPipefail(t *testing.T) {
+	var outBuf bytes.Buffer
+	cmd, err := Local{Shell: Shell{"bash", "-euo", "pipefail"}}.Cmd("false | true", &outBuf, ioutil.Discard, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Run(); err == nil {
+		t.Error("expected pipefail to fail the command")
+	}
+}
+
+func TestShellLocalFallback(t *testing.T) {
+	prevDefault := DefaultShell
+	defer func() { DefaultShell = prevDefault }()
+	DefaultShell = Shell{"_nosuchshell", "-e"}
+	if got := localShell(nil).String(); got != "sh -e" {
+		t.Errorf("want sh -e got %q", got)
+	}
+}
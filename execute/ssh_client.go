@@ -1,25 +1,38 @@
 package execute
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
 	"os"
-
-	"github.com/upsight/ron/color"
+	"os/signal"
+	"strings"
+	"time"
 
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 // SSH holds the ssh configuration and io.
 type SSH struct {
 	Config *SSHConfig
 	Stdin  io.Reader
+	// Stdout and Stderr receive the remote output as is, use Mux to
+	// prefix each line with the host when running on several hosts.
 	Stdout io.Writer
 	Stderr io.Writer
+	// Pty requests a pseudo terminal for commands. This merges stderr
+	// into stdout on most servers so it should only be used when running
+	// interactively.
+	Pty bool
+	// SudoPassword is written as the first line of stdin for commands
+	// wrapped with SudoCommand.
+	SudoPassword string
+	client       *ssh.Client
+	closers      []io.Closer
 }
 
 // SSHConfig is an individual ssh configuration for creating
@@ -32,16 +45,24 @@ type SSHConfig struct {
 	ProxyPort    int    `json:"proxy_port,omitempty" yaml:"proxy_port,omitempty"`
 	ProxyUser    string `json:"proxy_user,omitempty" yaml:"proxy_user,omitempty"`
 	IdentityFile string `json:"identity_file,omitempty" yaml:"identity_file,omitempty"`
+	Pty          *bool  `json:"pty,omitempty" yaml:"pty,omitempty"`
+	Sudo         bool   `json:"sudo,omitempty" yaml:"sudo,omitempty"`
+	SudoUser     string `json:"sudo_user,omitempty" yaml:"sudo_user,omitempty"`
 }
 
-// RunCommand will execute a command using the input environment variables.
-// It will establish a new session on every call.
-func (s *SSH) RunCommand(cmd string, envs map[string]string) error {
-	var (
-		conn       *ssh.Client
-		err        error
-		authMethod ssh.AuthMethod
-	)
+// Connect establishes the ssh connection, going through the proxy host
+// when one is configured. Once connected, RunCommand, Upload and Download
+// reuse the same connection until Close is called.
+func (s *SSH) Connect() error {
+	return s.ConnectContext(context.Background())
+}
+
+// ConnectContext is Connect which gives up dialing when ctx is done.
+func (s *SSH) ConnectContext(ctx context.Context) error {
+	if s.client != nil {
+		return nil
+	}
+	var authMethod ssh.AuthMethod
 	switch {
 	case s.Config.IdentityFile != "":
 		pemBytes, err := ioutil.ReadFile(s.Config.IdentityFile)
@@ -80,48 +101,108 @@ func (s *SSH) RunCommand(cmd string, envs map[string]string) error {
 			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
 		}
 		// connect to the bastion host
-		bastionClient, err := ssh.Dial("tcp", bastionAddr, bastionConfig)
+		bastionClient, err := dialContext(ctx, bastionAddr, bastionConfig)
 		if err != nil {
 			return fmt.Errorf("unable to connect: %s", err)
 		}
-		defer bastionClient.Close()
 
 		// dial a connection to the service host, from the bastion
 		bconn, err := bastionClient.Dial("tcp", targetAddr)
 		if err != nil {
+			bastionClient.Close()
 			return fmt.Errorf("unable to connect: %s", err)
 		}
-		defer bconn.Close()
 
 		ncc, chans, reqs, err := ssh.NewClientConn(bconn, targetAddr, targetConfig)
 		if err != nil {
+			bconn.Close()
+			bastionClient.Close()
 			return fmt.Errorf("failed to create conn: %s", err)
 		}
-		conn = ssh.NewClient(ncc, chans, reqs)
+		s.client = ssh.NewClient(ncc, chans, reqs)
+		s.closers = append(s.closers, bconn, bastionClient)
 	default:
-		conn, err = ssh.Dial("tcp", targetAddr, targetConfig)
+		conn, err := dialContext(ctx, targetAddr, targetConfig)
 		if err != nil {
 			return fmt.Errorf("unable to connect: %s", err)
 		}
+		s.client = conn
+	}
+	return nil
+}
+
+// dialContext connects to addr and performs the ssh handshake, giving up
+// when ctx is done or its deadline passes.
+func dialContext(ctx context.Context, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	ncc, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(ncc, chans, reqs), nil
+}
+
+// Close shuts down the connection established by Connect along with
+// any proxy connections.
+func (s *SSH) Close() error {
+	if s.client == nil {
+		return nil
+	}
+	err := s.client.Close()
+	for _, c := range s.closers {
+		c.Close()
 	}
+	s.client = nil
+	s.closers = nil
+	return err
+}
+
+// RunCommand will execute a command using the input environment variables.
+// It will establish a new session on every call, and a new connection if
+// Connect has not been called.
+func (s *SSH) RunCommand(cmd string, envs map[string]string) error {
+	return s.RunCommandContext(context.Background(), cmd, envs)
+}
 
-	defer conn.Close()
+// RunCommandContext is RunCommand which signals and closes the session
+// when ctx is done, returning the context error.
+func (s *SSH) RunCommandContext(ctx context.Context, cmd string, envs map[string]string) error {
+	if s.client == nil {
+		if err := s.ConnectContext(ctx); err != nil {
+			return err
+		}
+		defer s.Close()
+	}
 
-	session, err := conn.NewSession()
+	session, err := s.client.NewSession()
 	if err != nil {
 		return fmt.Errorf("failed to create session: %s", err)
 	}
 	defer session.Close()
 
-	modes := ssh.TerminalModes{
-		ssh.ECHO:          0,     // disable echoing
-		ssh.TTY_OP_ISPEED: 14400, // input speed = 14.4kbaud
-		ssh.TTY_OP_OSPEED: 14400, // output speed = 14.4kbaud
-	}
+	if s.Pty {
+		modes := ssh.TerminalModes{
+			ssh.ECHO:          0,     // disable echoing
+			ssh.TTY_OP_ISPEED: 14400, // input speed = 14.4kbaud
+			ssh.TTY_OP_OSPEED: 14400, // output speed = 14.4kbaud
+		}
 
-	err = session.RequestPty("xterm", 80, 40, modes)
-	if err != nil {
-		return fmt.Errorf("request for pseudo terminal failed: %s", err)
+		width, height := terminalSize()
+		err = session.RequestPty(terminalName(), height, width, modes)
+		if err != nil {
+			return fmt.Errorf("request for pseudo terminal failed: %s", err)
+		}
+		done := make(chan struct{})
+		defer close(done)
+		go forwardWindowChange(session, done)
 	}
 
 	err = s.prepareCommand(session, cmd, envs)
@@ -133,7 +214,18 @@ func (s *SSH) RunCommand(cmd string, envs map[string]string) error {
 	if err != nil {
 		return err
 	}
-	return session.Wait()
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Wait()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		session.Signal(ssh.SIGTERM)
+		session.Close()
+		return ctx.Err()
+	}
 }
 
 func (s *SSH) prepareCommand(session *ssh.Session, cmd string, envs map[string]string) error {
@@ -143,46 +235,73 @@ func (s *SSH) prepareCommand(session *ssh.Session, cmd string, envs map[string]s
 		}
 	}
 
-	if s.Stdin != nil {
+	if s.Stdin != nil || s.SudoPassword != "" {
 		stdin, err := session.StdinPipe()
 		if err != nil {
 			return fmt.Errorf("unable to setup stdin for session: %v", err)
 		}
-		go io.Copy(stdin, s.Stdin)
-	}
-
-	if s.Stdout != nil {
-		stdout, err := session.StdoutPipe()
-		if err != nil {
-			return fmt.Errorf("unable to setup stdout for session: %v", err)
-		}
-		scanner := bufio.NewScanner(stdout)
 		go func() {
-			for scanner.Scan() {
-				fmt.Fprintf(s.Stdout, color.Green("%s]")+" %s\n", s.Config.Host, scanner.Text())
+			if s.SudoPassword != "" {
+				io.WriteString(stdin, s.SudoPassword+"\n")
 			}
-			if err := scanner.Err(); err != nil {
-				fmt.Fprintln(s.Stderr, err)
+			if s.Stdin != nil {
+				io.Copy(stdin, s.Stdin)
 			}
 		}()
 	}
 
-	if s.Stderr != nil {
-		stderr, err := session.StderrPipe()
-		if err != nil {
-			return fmt.Errorf("unable to setup stderr for session: %v", err)
+	// the session waits for output to be copied before Wait returns.
+	session.Stdout = s.Stdout
+	session.Stderr = s.Stderr
+	return nil
+}
+
+// IsInteractive reports whether stdin is a terminal, in which case remote
+// commands default to running with a pseudo terminal and local commands
+// are made the foreground process group.
+var IsInteractive = func() bool {
+	return terminal.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// IsTerminal reports whether w is a file connected to a terminal.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && terminal.IsTerminal(int(f.Fd()))
+}
+
+// terminalSize returns the width and height of the local terminal,
+// defaulting to 80x40 when stdout is not a terminal.
+func terminalSize() (int, int) {
+	width, height, err := terminal.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 40
+	}
+	return width, height
+}
+
+// terminalName returns the local TERM, defaulting to xterm.
+func terminalName() string {
+	if term := os.Getenv("TERM"); term != "" {
+		return term
+	}
+	return "xterm"
+}
+
+// forwardWindowChange resizes the remote pseudo terminal whenever the
+// local terminal is resized until done is closed.
+func forwardWindowChange(session *ssh.Session, done chan struct{}) {
+	resize := make(chan os.Signal, 1)
+	notifyWindowChange(resize)
+	defer signal.Stop(resize)
+	for {
+		select {
+		case <-resize:
+			width, height := terminalSize()
+			session.WindowChange(height, width)
+		case <-done:
+			return
 		}
-		scanner := bufio.NewScanner(stderr)
-		go func() {
-			for scanner.Scan() {
-				fmt.Fprintf(s.Stderr, color.Red("%s]")+" %s\n", s.Config.Host, scanner.Text())
-			}
-			if err := scanner.Err(); err != nil {
-				fmt.Fprintln(s.Stderr, err)
-			}
-		}()
 	}
-	return nil
 }
 
 // NewSSH will initialize a new ssh configuration for use with RunCommand.
@@ -197,3 +316,36 @@ func NewSSH(conf *SSHConfig, stdin io.Reader, stdout, stderr io.Writer) (*SSH, e
 	}
 	return s, nil
 }
+
+// SudoCommand wraps cmd to be run through sudo as user, or root if user
+// is empty. When withPassword is set the sudo password is read from the
+// first line of stdin and used to validate sudo before cmd runs, so the
+// password is never passed on to cmd itself. Otherwise sudo must not
+// require a password.
+func SudoCommand(cmd, user string, withPassword bool) string {
+	sudo := "sudo -n"
+	if user != "" {
+		sudo += " -u " + ShellQuote(user)
+	}
+	sudo += " -- sh -c " + ShellQuote(cmd)
+	if !withPassword {
+		return sudo
+	}
+	return `IFS= read -r RON_SUDO_PASSWORD; ` +
+		`printf '%s\n' "$RON_SUDO_PASSWORD" | sudo -S -p '' -v; ` +
+		`unset RON_SUDO_PASSWORD; ` + sudo
+}
+
+// ShellQuote single quotes s for use as one word in a posix shell.
+func ShellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// ReadPassword writes prompt to w and reads a password from the terminal
+// on stdin without echoing it.
+var ReadPassword = func(prompt string, w io.Writer) (string, error) {
+	fmt.Fprint(w, prompt)
+	b, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(w)
+	return string(b), err
+}
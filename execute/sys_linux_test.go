@@ -0,0 +1,173 @@
+package execute
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// openPty opens a new pseudo terminal returning its master and slave.
+func openPty() (*os.File, *os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	var unlock int32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+		master.Close()
+		return nil, nil, errno
+	}
+	var n uint32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); errno != 0 {
+		master.Close()
+		return nil, nil, errno
+	}
+	slave, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+	return master, slave, nil
+}
+
+// TestExecuteTerminalHelper runs commands from the terminal started by
+// runInTerminal, printing what the parent test checks.
+func TestExecuteTerminalHelper(t *testing.T) {
+	mode := os.Getenv("RON_TEST_TERMINAL")
+	switch mode {
+	case "read":
+		fmt.Println("ready")
+		status, err := Command("read x; echo got $x", os.Stdout, os.Stderr, nil)
+		if status != 0 || err != nil {
+			fmt.Println("failed", status, err)
+		}
+	case "cancel", "interrupt":
+		// a file rather than an io.Pipe so Wait doesn't wait for the
+		// grandchild to close its output.
+		r, w, err := os.Pipe()
+		if err != nil {
+			fmt.Println("failed", err)
+			os.Exit(1)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		// background jobs in a non interactive shell ignore SIGINT.
+		cmd, err := CommandNoWaitContext(ctx, "sleep 300 & echo $!; wait", w, os.Stderr, nil)
+		if err != nil {
+			fmt.Println("failed", err)
+			os.Exit(1)
+		}
+		w.Close()
+		line, _ := bufio.NewReader(r).ReadString('\n')
+		pid, _ := strconv.Atoi(strings.TrimSpace(line))
+		fmt.Println("ready")
+		if mode == "cancel" {
+			cancel()
+		}
+		WaitContext(ctx, cmd)
+		time.Sleep(50 * time.Millisecond)
+		if pid == 0 || !processGone(pid) {
+			syscall.Kill(pid, syscall.SIGKILL)
+			fmt.Println("grandchild running")
+		} else {
+			fmt.Println("grandchild killed")
+		}
+	default:
+		return
+	}
+	if pgrp, err := tcgetpgrp(os.Stdin.Fd()); err == nil && pgrp == syscall.Getpgrp() {
+		fmt.Println("foreground restored")
+	}
+	os.Exit(0)
+}
+
+// runInTerminal runs TestExecuteTerminalHelper in mode as the foreground
+// process group of a new pseudo terminal, writing input to it, and
+// returns the terminal's output. input is written once the helper prints
+// ready.
+func runInTerminal(t *testing.T, mode, input string) string {
+	master, slave, err := openPty()
+	if err != nil {
+		t.Skipf("no pseudo terminal: %v", err)
+	}
+	defer master.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=TestExecuteTerminalHelper")
+	cmd.Env = append(os.Environ(), "RON_TEST_TERMINAL="+mode)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	slave.Close()
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	output := make(chan string, 1)
+	go func() {
+		var out bytes.Buffer
+		b := make([]byte, 1024)
+		ready := false
+		for {
+			n, err := master.Read(b)
+			out.Write(b[:n])
+			if !ready && strings.Contains(out.String(), "ready") {
+				ready = true
+				master.Write([]byte(input))
+			}
+			if err != nil {
+				break
+			}
+		}
+		output <- out.String()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected exit 0 got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		cmd.Process.Kill()
+		t.Fatal("command in the terminal did not finish")
+	}
+	// closing the last slave makes reads from the master fail.
+	select {
+	case out := <-output:
+		return out
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected terminal output")
+	}
+	return ""
+}
+
+func TestExecuteReadTerminal(t *testing.T) {
+	out := runInTerminal(t, "read", "hello\n")
+	for _, want := range []string{"got hello", "foreground restored"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in %q", want, out)
+		}
+	}
+}
+
+func TestExecuteTerminalGroup(t *testing.T) {
+	// cancelled by ron, and interrupted by Ctrl-C on the terminal which
+	// only the foreground group receives.
+	for mode, input := range map[string]string{"cancel": "", "interrupt": "\x03"} {
+		out := runInTerminal(t, mode, input)
+		for _, want := range []string{"grandchild killed", "foreground restored"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s expected %s in %q", mode, want, out)
+			}
+		}
+	}
+}
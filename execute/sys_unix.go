@@ -0,0 +1,93 @@
+//go:build !windows
+// +build !windows
+
+package execute
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// forwardSignals are the signals passed on to a running process group.
+var forwardSignals = []os.Signal{syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT}
+
+// notifyWindowChange relays local terminal resize signals to c.
+func notifyWindowChange(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGWINCH)
+}
+
+// setProcessGroup starts cmd in a new process group so that it and
+// any children can be signalled together. When cmd reads from the
+// terminal ron is the foreground process group of, cmd's group is made
+// the foreground group instead so reading doesn't stop it with SIGTTIN.
+// restoreForeground gives the terminal back once cmd exits.
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	cmd.SysProcAttr.Foreground = false
+	if f, ok := cmd.Stdin.(*os.File); ok && IsInteractive() && f == os.Stdin {
+		if pgrp, err := tcgetpgrp(f.Fd()); err == nil && pgrp == syscall.Getpgrp() {
+			cmd.SysProcAttr.Foreground = true
+			cmd.SysProcAttr.Ctty = int(f.Fd())
+		}
+	}
+}
+
+// foregroundMu serializes changes to the terminal's foreground group.
+var foregroundMu sync.Mutex
+
+// restoreForeground makes ron's process group the terminal's foreground
+// group again if it was given to cmd and cmd still has it.
+func restoreForeground(cmd *exec.Cmd) {
+	if cmd.Process == nil || cmd.SysProcAttr == nil || !cmd.SysProcAttr.Foreground {
+		return
+	}
+	foregroundMu.Lock()
+	defer foregroundMu.Unlock()
+	if pgrp, err := tcgetpgrp(os.Stdin.Fd()); err != nil || pgrp != cmd.Process.Pid {
+		return
+	}
+	// setting the foreground group from a background group sends SIGTTOU,
+	// which stops the process unless it is ignored.
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+	tcsetpgrp(os.Stdin.Fd(), syscall.Getpgrp())
+}
+
+// tcgetpgrp returns the foreground process group of the terminal fd.
+func tcgetpgrp(fd uintptr) (int, error) {
+	var pgrp int32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCGPGRP, uintptr(unsafe.Pointer(&pgrp))); errno != 0 {
+		return 0, errno
+	}
+	return int(pgrp), nil
+}
+
+// tcsetpgrp makes pgrp the foreground process group of the terminal fd.
+func tcsetpgrp(fd uintptr, pgrp int) error {
+	p := int32(pgrp)
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCSPGRP, uintptr(unsafe.Pointer(&p))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// signalGroup sends sig to the process group of cmd, falling back to
+// only the process if it is not a group leader.
+func signalGroup(cmd *exec.Cmd, sig os.Signal) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	if s, ok := sig.(syscall.Signal); ok {
+		if err := syscall.Kill(-cmd.Process.Pid, s); err == nil {
+			return nil
+		}
+	}
+	return cmd.Process.Signal(sig)
+}
@@ -0,0 +1,93 @@
+//go:build !windows
+// +build !windows
+
+package execute
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// processGone reports whether pid has exited, zombies included as they
+// may not be reaped when the test runs as pid 1.
+func processGone(pid int) bool {
+	stat, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return syscall.Kill(pid, 0) != nil
+	}
+	fields := strings.Fields(string(stat))
+	return len(fields) > 2 && fields[2] == "Z"
+}
+
+func TestExecuteWaitInterruptGroup(t *testing.T) {
+	prevGracePeriod := GracePeriod
+	defer func() { GracePeriod = prevGracePeriod }()
+	GracePeriod = 200 * time.Millisecond
+
+	r, w := io.Pipe()
+	// background jobs in a non interactive shell ignore SIGINT.
+	cmd, err := CommandNoWait("sleep 30 & echo $!; wait", w, ioutil.Discard, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil {
+		t.Fatal(err)
+	}
+	interrupt := make(chan os.Signal, 1)
+	interrupt <- syscall.SIGINT
+	if err := waitInterrupt(context.Background(), interrupt, cmd); err == nil {
+		t.Error("expected interrupted error")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if !processGone(pid) {
+		syscall.Kill(pid, syscall.SIGKILL)
+		t.Fatalf("expected background process %d to be killed", pid)
+	}
+}
+
+func TestExecuteWaitInterruptGracePeriod(t *testing.T) {
+	prevGracePeriod := GracePeriod
+	defer func() { GracePeriod = prevGracePeriod }()
+	GracePeriod = 100 * time.Millisecond
+
+	cmd, err := CommandNoWait("trap '' INT; sleep 30", ioutil.Discard, ioutil.Discard, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	interrupt := make(chan os.Signal, 1)
+	interrupt <- syscall.SIGINT
+	start := time.Now()
+	waitInterrupt(context.Background(), interrupt, cmd)
+	if elapsed := time.Since(start); elapsed < GracePeriod || elapsed > 5*time.Second {
+		t.Errorf("expected kill after grace period got %s", elapsed)
+	}
+	status := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	if !status.Signaled() || status.Signal() != syscall.SIGKILL {
+		t.Errorf("expected SIGKILL got %v", status)
+	}
+}
+
+func TestExecuteWaitNoSignal(t *testing.T) {
+	cmd, err := CommandNoWait("exit 3", ioutil.Discard, ioutil.Discard, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Wait(cmd)
+	if GetExitStatus(err) != 3 {
+		t.Errorf("expected exit status 3 got %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+package execute
+
+import (
+	"os"
+	"os/exec"
+)
+
+// forwardSignals are the signals passed on to a running process.
+var forwardSignals = []os.Signal{os.Interrupt}
+
+// notifyWindowChange is a no-op as windows has no resize signal.
+func notifyWindowChange(c chan<- os.Signal) {}
+
+// setProcessGroup is a no-op on windows.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// restoreForeground is a no-op on windows.
+func restoreForeground(cmd *exec.Cmd) {}
+
+// signalGroup kills the process as windows cannot deliver other signals.
+func signalGroup(cmd *exec.Cmd, sig os.Signal) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
@@ -1,10 +1,15 @@
 module github.com/upsight/ron
 
+go 1.20
+
 require (
-	github.com/pkar/log v0.0.0-20160224192742-0ce5e78910d9 // indirect
-	github.com/pkar/runit v0.1.0
+	github.com/fsnotify/fsnotify v1.4.7
 	golang.org/x/crypto v0.0.0-20181112202954-3d3f9f413869
 	golang.org/x/net v0.0.0-20181108082009-03003ca0c849
-	golang.org/x/text v0.3.0 // indirect
 	gopkg.in/yaml.v2 v2.2.1
 )
+
+require (
+	golang.org/x/sys v0.0.0-20181107165924-66b7b1311ac8 // indirect
+	golang.org/x/text v0.3.0 // indirect
+)
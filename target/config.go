@@ -11,6 +11,7 @@ import (
 	yaml "gopkg.in/yaml.v2"
 
 	"github.com/upsight/ron/color"
+	"github.com/upsight/ron/execute"
 	fi "github.com/upsight/ron/file"
 	template "github.com/upsight/ron/template"
 )
@@ -46,13 +47,28 @@ func init() {
 
 // ConfigFile is used to unmarshal configuration files.
 type ConfigFile struct {
-	Envs    []map[string]string `json:"envs" yaml:"envs"`
-	Remotes *Remotes            `json:"remotes" yaml:"remotes"`
-	Targets map[string]struct {
-		Before      []string `json:"before" yaml:"before"`
-		After       []string `json:"after" yaml:"after"`
-		Cmd         string   `json:"cmd" yaml:"cmd"`
-		Description string   `json:"description" yaml:"description"`
+	Envs      []map[string]string `json:"envs" yaml:"envs"`
+	Remotes   *Remotes            `json:"remotes" yaml:"remotes"`
+	Shell     execute.Shell       `json:"shell,omitempty" yaml:"shell,omitempty"`
+	Processes map[string]*Process `json:"processes,omitempty" yaml:"processes,omitempty"`
+	Targets   map[string]struct {
+		Before      []string            `json:"before" yaml:"before"`
+		After       []string            `json:"after" yaml:"after"`
+		Cmd         string              `json:"cmd" yaml:"cmd"`
+		Description string              `json:"description" yaml:"description"`
+		Image       string              `json:"image,omitempty" yaml:"image,omitempty"`
+		Volumes     []string            `json:"volumes,omitempty" yaml:"volumes,omitempty"`
+		Workdir     string              `json:"workdir,omitempty" yaml:"workdir,omitempty"`
+		Shell       execute.Shell       `json:"shell,omitempty" yaml:"shell,omitempty"`
+		Upload      []*execute.Transfer `json:"upload,omitempty" yaml:"upload,omitempty"`
+		Download    []*execute.Transfer `json:"download,omitempty" yaml:"download,omitempty"`
+		Pty         *bool               `json:"pty,omitempty" yaml:"pty,omitempty"`
+		Sudo        bool                `json:"sudo,omitempty" yaml:"sudo,omitempty"`
+		SudoUser    string              `json:"sudo_user,omitempty" yaml:"sudo_user,omitempty"`
+		Watch       []string            `json:"watch,omitempty" yaml:"watch,omitempty"`
+		WaitFor     *WaitFor            `json:"wait_for,omitempty" yaml:"wait_for,omitempty"`
+		Background  bool                `json:"background,omitempty" yaml:"background,omitempty"`
+		Teardown    string              `json:"teardown,omitempty" yaml:"teardown,omitempty"`
 	} `json:"targets" yaml:"targets"`
 }
 
@@ -76,10 +92,12 @@ func (c *ConfigFile) RemotesString() string {
 
 // RawConfig contains the raw strings from a loaded config file.
 type RawConfig struct {
-	Filepath string
-	Envs     string
-	Remotes  string
-	Targets  string
+	Filepath  string
+	Envs      string
+	Remotes   string
+	Shell     string
+	Processes string
+	Targets   string
 }
 
 // extractConfigError parses the error for line number and then
@@ -291,15 +309,25 @@ var LoadConfigFile = func(path string) (*RawConfig, error) {
 	if err != nil {
 		return nil, err
 	}
+	shell, err := yaml.Marshal(c.Shell)
+	if err != nil {
+		return nil, err
+	}
+	processes, err := yaml.Marshal(c.Processes)
+	if err != nil {
+		return nil, err
+	}
 	targets, err := yaml.Marshal(c.Targets)
 	if err != nil {
 		return nil, err
 	}
 	return &RawConfig{
-		Envs:     string(envs),
-		Filepath: path,
-		Remotes:  string(remotes),
-		Targets:  string(targets),
+		Envs:      string(envs),
+		Filepath:  path,
+		Remotes:   string(remotes),
+		Shell:     string(shell),
+		Processes: string(processes),
+		Targets:   string(targets),
 	}, nil
 }
 
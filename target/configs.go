@@ -15,11 +15,14 @@ import (
 
 // Configs is a mapping of filename to target file.
 type Configs struct {
-	RemoteEnv   string               // The remote hosts to run the command on. This is (file):env
-	RemoteHosts []*execute.SSHConfig // a list of remote hosts to execute on.
-	Files       []*File
-	StdOut      io.Writer
-	StdErr      io.Writer
+	RemoteEnv    string               // The remote hosts to run the command on. This is (file):env
+	RemoteHosts  []*execute.SSHConfig // a list of remote hosts to execute on.
+	SudoPassword string               // the password fed to sudo on remote hosts.
+	Output       string               // how concurrent output is shown, see execute.OutputPrefixed.
+	Recorders    []Recorder           // receive an event as each target starts and finishes.
+	Files        []*File
+	StdOut       io.Writer
+	StdErr       io.Writer
 }
 
 // NewConfigs takes a default set of yaml in config format and then
@@ -50,6 +53,14 @@ func NewConfigs(configs []*RawConfig, remoteEnv string, stdOut io.Writer, stdErr
 		if err := yaml.Unmarshal([]byte(config.Remotes), &remotes); err != nil {
 			return nil, err
 		}
+		var shell execute.Shell
+		if err := yaml.Unmarshal([]byte(config.Shell), &shell); err != nil {
+			return nil, err
+		}
+		var processes map[string]*Process
+		if err := yaml.Unmarshal([]byte(config.Processes), &processes); err != nil {
+			return nil, err
+		}
 		// initialize io for each target.
 		for name, target := range targets {
 			target.W = stdOut
@@ -65,10 +76,16 @@ func NewConfigs(configs []*RawConfig, remoteEnv string, stdOut io.Writer, stdErr
 			Filepath:  config.Filepath,
 			Targets:   targets,
 			Remotes:   remotes,
+			Shell:     shell,
+			Processes: processes,
 		}
 		for _, t := range targets {
 			t.File = f
 		}
+		for name, p := range processes {
+			p.Name = name
+			p.File = f
+		}
 		e, err := NewEnv(parentFile, config, osEnvs, stdOut)
 		if err != nil {
 			return nil, err
@@ -159,3 +159,41 @@ func TestNewConfigsBadNew(t *testing.T) {
 		t.Fatal("expected err for invalid new config")
 	}
 }
+
+func TestNewConfigsShell(t *testing.T) {
+	tc, err := NewConfigs([]*RawConfig{&RawConfig{
+		Filepath: "testdata/shell.yaml",
+		Shell:    "bash -euo pipefail",
+		Targets:  "fail:\n  cmd: false | true\n",
+	}}, "", &bytes.Buffer{}, &bytes.Buffer{})
+	ok(t, err)
+	equals(t, "bash -euo pipefail", tc.Files[0].Shell.String())
+	target, _ := tc.Target("fail")
+	status, _, _ := target.Run()
+	if status == 0 {
+		t.Fatal("expected file shell pipefail to fail the target")
+	}
+}
+
+func TestConfigsProcesses(t *testing.T) {
+	tc, err := NewConfigs([]*RawConfig{
+		&RawConfig{
+			Filepath:  "testdata/ron.yaml",
+			Processes: "web: go run ./cmd/web\nworker:\n  cmd: ./worker\n  watch:\n    - \"*.go\"\n",
+		},
+		&RawConfig{
+			Filepath:  "testdata/default.yaml",
+			Processes: "web: ignored\nassets: npm run watch\n",
+		},
+	}, "", nil, nil)
+	ok(t, err)
+	processes := tc.Processes()
+	equals(t, 3, len(processes))
+	assets, web, worker := processes[0], processes[1], processes[2]
+	equals(t, "assets", assets.Name)
+	equals(t, "npm run watch", assets.Cmd)
+	equals(t, "default", assets.File.Basename())
+	equals(t, "go run ./cmd/web", web.Cmd)
+	equals(t, "./worker", worker.Cmd)
+	equals(t, []string{"*.go"}, worker.Watch)
+}
@@ -194,6 +194,21 @@ func (e *Env) Getenv(key string) string {
 	return v
 }
 
+// Keys returns the names of the envs declared in the yaml config,
+// including those of the parent, in order of definition.
+func (e *Env) Keys() []string {
+	keys := []string{}
+	if e.parent != nil {
+		keys = append(keys, e.parent.Env.Keys()...)
+	}
+	for _, k := range e.keyOrder {
+		if !keyIn(k, keys) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
 // List prints to the underlying writer a list of
 // the configured env based on overriden environment
 // variables and default yaml ones.
@@ -99,6 +99,16 @@ func TestEnv_processParentFile(t *testing.T) {
 	equals(t, want, got)
 }
 
+func TestEnv_Keys(t *testing.T) {
+	writer := &bytes.Buffer{}
+	eParent, err := NewEnv(nil, &RawConfig{Envs: "- APP: parent\n- HOME: /root"}, ParseOSEnvs([]string{"GOPATH=/go"}), writer)
+	ok(t, err)
+	f := &File{Env: eParent}
+	e, err := NewEnv(f, &RawConfig{Envs: testNewEnvConfig}, ParseOSEnvs([]string{"GOPATH=/go"}), writer)
+	ok(t, err)
+	equals(t, []string{"APP", "HOME", "UNAME", "RON", "CMD", "ENVS", "NOOP"}, e.Keys())
+}
+
 func TestEnv_processParentFileProcessEnv(t *testing.T) {
 	writer := &bytes.Buffer{}
 	parentEnv := `
@@ -0,0 +1,244 @@
+package target
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Event types recorded while running targets.
+const (
+	EventStarted  = "started"
+	EventFinished = "finished"
+	EventSkipped  = "skipped"
+)
+
+// tailSize is the number of trailing stdout and stderr bytes kept for each
+// finished target.
+const tailSize = 4096
+
+// Event is a single step of a run, one is recorded when a target starts,
+// finishes or is skipped because a before target failed. Host is only set
+// for targets run on remote hosts.
+type Event struct {
+	Time     time.Time     `json:"time"`
+	Type     string        `json:"type"`
+	Target   string        `json:"target"`
+	Host     string        `json:"host,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Status   int           `json:"status"`
+	Error    string        `json:"error,omitempty"`
+	Stdout   string        `json:"stdout,omitempty"`
+	Stderr   string        `json:"stderr,omitempty"`
+}
+
+// Recorder receives the events of a run, it must be safe to call from
+// multiple goroutines.
+type Recorder interface {
+	Record(e *Event)
+}
+
+// RunLog records events as json lines to a file and keeps them for
+// printing a summary once the run is done.
+type RunLog struct {
+	Path   string
+	Events []*Event
+
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+// NewRunLog creates a new timestamped log file in dir, creating the
+// directory if needed. The directory is given a .gitignore ignoring all of
+// its files so the logs aren't committed in the project using ron.
+func NewRunLog(dir string) (*RunLog, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	gitignore := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(gitignore); os.IsNotExist(err) {
+		if err := ioutil.WriteFile(gitignore, []byte("*\n"), 0644); err != nil {
+			return nil, err
+		}
+	}
+	path := filepath.Join(dir, time.Now().Format("2006-01-02T15-04-05.000000")+".jsonl")
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &RunLog{Path: path, f: f, enc: json.NewEncoder(f)}, nil
+}
+
+// Record writes the event to the log file.
+func (r *RunLog) Record(e *Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Events = append(r.Events, e)
+	if r.enc != nil {
+		r.enc.Encode(e)
+	}
+}
+
+// Close closes the log file.
+func (r *RunLog) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f, r.enc = nil, nil
+	return err
+}
+
+// LastRunLog returns the path of the most recent log file in dir.
+func LastRunLog(dir string) (string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("no run logs found in %s", dir)
+	}
+	sort.Strings(files)
+	return files[len(files)-1], nil
+}
+
+// Prune removes all but the keep most recent log files in the directory
+// of the log. The log itself is always kept and counts towards keep, so a
+// keep of 0 leaves only it.
+func (r *RunLog) Prune(keep int) error {
+	if keep < 0 {
+		return fmt.Errorf("invalid number of run logs to keep %d", keep)
+	}
+	if r.Path == "" {
+		return nil
+	}
+	files, err := filepath.Glob(filepath.Join(filepath.Dir(r.Path), "*.jsonl"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	older := []string{}
+	for _, f := range files {
+		if f != r.Path {
+			older = append(older, f)
+		}
+	}
+	if keep > 0 {
+		keep--
+	}
+	if len(older) <= keep {
+		return nil
+	}
+	for _, f := range older[:len(older)-keep] {
+		if err := os.Remove(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadRunLog reads the events from a log file.
+func ReadRunLog(path string) ([]*Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	events := []*Event{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		e := &Event{}
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
+
+// Summary writes a timing table of the finished and skipped targets in
+// the order they completed, followed by the total time of the run.
+func Summary(w io.Writer, events []*Event) {
+	if len(events) == 0 {
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TARGET\tHOST\tSTATUS\tDURATION")
+	first, last := events[0].Time, events[0].Time
+	for _, e := range events {
+		if e.Time.Before(first) {
+			first = e.Time
+		}
+		if e.Time.After(last) {
+			last = e.Time
+		}
+		var status string
+		switch {
+		case e.Type == EventSkipped:
+			status = "skipped"
+		case e.Type != EventFinished:
+			continue
+		case e.Status == 0 && e.Error == "":
+			status = "ok"
+		case e.Error == context.Canceled.Error():
+			status = "cancelled"
+		default:
+			status = fmt.Sprintf("failed (%d)", e.Status)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", e.Target, e.Host, status, roundDuration(e.Duration))
+	}
+	fmt.Fprintf(tw, "total\t\t\t%s\n", roundDuration(last.Sub(first)))
+	tw.Flush()
+}
+
+// roundDuration shortens d for display.
+func roundDuration(d time.Duration) time.Duration {
+	if d > time.Second {
+		return d.Round(10 * time.Millisecond)
+	}
+	return d.Round(time.Millisecond)
+}
+
+// tailWriter keeps the last size bytes written to it.
+type tailWriter struct {
+	mu   sync.Mutex
+	size int
+	buf  []byte
+}
+
+func newTailWriter(size int) *tailWriter {
+	return &tailWriter{size: size}
+}
+
+func (t *tailWriter) Write(b []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, b...)
+	if len(t.buf) > t.size {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-t.size:]...)
+	}
+	return len(b), nil
+}
+
+func (t *tailWriter) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
@@ -0,0 +1,146 @@
+package target
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTargetRecordEvents(t *testing.T) {
+	tc, _, _ := createTestConfigs(t, nil, nil)
+	log := &RunLog{}
+	tc.Recorders = []Recorder{log}
+	target, _ := tc.Target("ron:prep")
+	status, _, err := target.Run()
+	ok(t, err)
+	equals(t, 0, status)
+
+	got := []string{}
+	for _, e := range log.Events {
+		got = append(got, e.Type+" "+e.Target)
+	}
+	want := []string{
+		"started ron:hello", "finished ron:hello",
+		"started ron:prep", "finished ron:prep",
+		"started ron:goodbye", "finished ron:goodbye",
+	}
+	equals(t, want, got)
+	equals(t, "goodbye\n", log.Events[5].Stdout)
+}
+
+func TestTargetRecordEventsSkipped(t *testing.T) {
+	tc, _, _ := createTestConfigs(t, nil, nil)
+	log := &RunLog{}
+	tc.Recorders = []Recorder{log}
+	target, _ := tc.Target("ron:prepBeforeErr")
+	status, _, err := target.Run()
+	if status == 0 || err == nil {
+		t.Fatalf("expected failure got %d %v", status, err)
+	}
+	equals(t, 3, len(log.Events))
+	finished, skipped := log.Events[1], log.Events[2]
+	equals(t, EventFinished, finished.Type)
+	equals(t, "ron:err", finished.Target)
+	equals(t, 127, finished.Status)
+	if !strings.Contains(finished.Stderr, "me_garbage") {
+		t.Errorf("expected output tail got %q", finished.Stderr)
+	}
+	equals(t, EventSkipped, skipped.Type)
+	equals(t, "ron:prepBeforeErr", skipped.Target)
+}
+
+func TestRunLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ronlogs")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = LastRunLog(dir)
+	if err == nil {
+		t.Fatal("expected no run logs error")
+	}
+
+	log, err := NewRunLog(filepath.Join(dir, "logs"))
+	ok(t, err)
+	start := time.Now()
+	log.Record(&Event{Time: start, Type: EventStarted, Target: "ron:a"})
+	log.Record(&Event{Time: start.Add(time.Second), Type: EventFinished, Target: "ron:a", Duration: time.Second})
+	log.Record(&Event{Time: start.Add(2 * time.Second), Type: EventFinished, Target: "ron:b", Host: "example.com", Status: 2, Error: "exit status 2"})
+	ok(t, log.Close())
+
+	path, err := LastRunLog(filepath.Join(dir, "logs"))
+	ok(t, err)
+	equals(t, log.Path, path)
+	events, err := ReadRunLog(path)
+	ok(t, err)
+	equals(t, 3, len(events))
+	equals(t, "example.com", events[2].Host)
+	equals(t, time.Second, events[1].Duration)
+
+	gitignore, err := ioutil.ReadFile(filepath.Join(dir, "logs", ".gitignore"))
+	ok(t, err)
+	equals(t, "*\n", string(gitignore))
+
+	out := &bytes.Buffer{}
+	Summary(out, events)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	equals(t, 4, len(lines))
+	for i, want := range [][]string{
+		{"TARGET", "HOST", "STATUS", "DURATION"},
+		{"ron:a", "ok", "1s"},
+		{"ron:b", "example.com", "failed", "(2)", "0s"},
+		{"total", "2s"},
+	} {
+		equals(t, want, strings.Fields(lines[i]))
+	}
+}
+
+func TestRunLogPrune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ronlogs")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	log, err := NewRunLog(dir)
+	ok(t, err)
+	defer log.Close()
+	older := []string{"2026-01-01T00-00-00.000000.jsonl", "2026-01-02T00-00-00.000000.jsonl", "2026-01-03T00-00-00.000000.jsonl"}
+	for _, name := range older {
+		ok(t, ioutil.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+	logs := func() []string {
+		files, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+		ok(t, err)
+		for i := range files {
+			files[i] = filepath.Base(files[i])
+		}
+		return files
+	}
+	current := filepath.Base(log.Path)
+
+	if err := log.Prune(-1); err == nil {
+		t.Error("expected an error for a negative keep")
+	}
+	equals(t, 4, len(logs()))
+	ok(t, log.Prune(5))
+	equals(t, 4, len(logs()))
+	ok(t, log.Prune(2))
+	equals(t, []string{older[2], current}, logs())
+	ok(t, log.Prune(0))
+	equals(t, []string{current}, logs())
+	_, err = os.Stat(filepath.Join(dir, ".gitignore"))
+	ok(t, err)
+
+	ok(t, (&RunLog{}).Prune(0))
+}
+
+func TestTailWriter(t *testing.T) {
+	w := newTailWriter(4)
+	w.Write([]byte("ab"))
+	w.Write([]byte("cdef"))
+	equals(t, "cdef", w.String())
+	w.Write([]byte("g"))
+	equals(t, "defg", w.String())
+}
@@ -3,6 +3,8 @@ package target
 import (
 	"path/filepath"
 	"strings"
+
+	"github.com/upsight/ron/execute"
 )
 
 // File is a mapping of the config file to its parsed envs and targets.
@@ -16,6 +18,10 @@ type File struct {
 	Env *Env
 	// Remotes is a mapping of environment to remote hosts.
 	Remotes Remotes
+	// Shell is the default shell for the files targets.
+	Shell execute.Shell
+	// Processes are the files long running commands.
+	Processes map[string]*Process
 }
 
 // Basename will return the Filepath name of file without the extension.
@@ -3,6 +3,7 @@
 package target
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
@@ -28,28 +29,49 @@ func NewMake(configs *Configs) (*Make, error) {
 
 // Run executes the given target name.
 func (m *Make) Run(names ...string) error {
+	return m.RunContext(context.Background(), names...)
+}
+
+// RunContext is Run which stops running targets when ctx is done. Any
+// background targets started are stopped once all names have run.
+func (m *Make) RunContext(ctx context.Context, names ...string) (err error) {
+	ctx, stopServices := withServices(ctx)
+	defer func() {
+		if serr := stopServices(); serr != nil && err == nil {
+			err = serr
+		}
+	}()
 	for _, name := range names {
 		target, ok := m.Configs.Target(name)
 		if !ok {
 			return fmt.Errorf("%s target not found", name)
 		}
 		if len(m.Configs.RemoteHosts) > 0 {
+			outMux := execute.NewMux(m.Configs.StdOut, m.Configs.Output)
+			errMux := execute.NewMux(m.Configs.StdErr, m.Configs.Output)
 			wg := &sync.WaitGroup{}
 			for _, h := range m.Configs.RemoteHosts {
 				wg.Add(1)
 				go func(host *execute.SSHConfig) {
 					defer wg.Done()
-					status, out, err := target.RunRemote(host)
+					stdOut := outMux.Writer(host.Host)
+					defer stdOut.Close()
+					stdErr := errMux.Writer(host.Host)
+					defer stdErr.Close()
+					status, out, err := target.runRemote(ctx, host, stdOut, stdErr)
 					if status != 0 || err != nil {
-						msg := fmt.Sprintf("%s] %d %s %v\n", host.Host, status, out, err)
-						m.Configs.StdErr.Write([]byte(color.Red(msg)))
+						msg := fmt.Sprintf("%d %s %v\n", status, out, err)
+						stdErr.Write([]byte(color.Red(msg)))
 						return
 					}
 				}(h)
 			}
 			wg.Wait()
+			if err := ctx.Err(); err != nil {
+				return err
+			}
 		} else {
-			status, out, err := target.Run()
+			status, out, err := target.RunContext(ctx)
 			if status != 0 || err != nil {
 				return fmt.Errorf("%d %s %v", status, out, err)
 			}
@@ -1,6 +1,7 @@
 package target
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -56,3 +57,15 @@ func TestMakeRunNoTarget(t *testing.T) {
 		t.Fatal("expected target not found")
 	}
 }
+
+func TestMakeRunContext(t *testing.T) {
+	tc, tcW, _ := createTestConfigs(t, nil, nil)
+	m, _ := NewMake(tc)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := m.RunContext(ctx, "ron:prep")
+	if err == nil {
+		t.Fatal("expected cancelled error")
+	}
+	equals(t, "", tcW.String())
+}
@@ -0,0 +1,47 @@
+package target
+
+import "sort"
+
+// Process is a long running command from the processes section of a
+// config file, supervised by ron cmd.
+type Process struct {
+	File *File  `json:"-" yaml:"-"`
+	Name string `json:"-" yaml:"-"`
+	Cmd  string `json:"cmd" yaml:"cmd"`
+	// Watch is a list of globs relative to the working directory which
+	// restart the process when a matching file changes.
+	Watch []string `json:"watch,omitempty" yaml:"watch,omitempty"`
+}
+
+// UnmarshalYAML allows a process to be given as just its cmd.
+func (p *Process) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var cmd string
+	if err := unmarshal(&cmd); err == nil {
+		p.Cmd = cmd
+		return nil
+	}
+	type process Process
+	return unmarshal((*process)(p))
+}
+
+// Processes returns the processes of all config files sorted by name. If
+// more than one file has a process with the same name the highest priority
+// file is used.
+func (tc *Configs) Processes() []*Process {
+	found := map[string]*Process{}
+	for _, f := range tc.Files {
+		for name, p := range f.Processes {
+			if _, ok := found[name]; !ok {
+				found[name] = p
+			}
+		}
+	}
+	processes := []*Process{}
+	for _, p := range found {
+		processes = append(processes, p)
+	}
+	sort.Slice(processes, func(i, j int) bool {
+		return processes[i].Name < processes[j].Name
+	})
+	return processes
+}
@@ -0,0 +1,167 @@
+package target
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Report formats for a run.
+const (
+	ReportJUnit  = "junit"
+	ReportGitHub = "github"
+)
+
+// annotationLines is the number of trailing stderr lines included in a
+// failure annotation.
+const annotationLines = 10
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+	SystemErr string        `xml:"system-err,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Body    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the finished and skipped targets of a run as JUnit
+// XML test cases. Targets run on remote hosts are a test case per host
+// with the host as the classname.
+func WriteJUnit(w io.Writer, events []*Event) error {
+	suite := junitTestSuite{Name: "ron"}
+	var first, last time.Time
+	for _, e := range events {
+		if first.IsZero() || e.Time.Before(first) {
+			first = e.Time
+		}
+		if e.Time.After(last) {
+			last = e.Time
+		}
+		if e.Type != EventFinished && e.Type != EventSkipped {
+			continue
+		}
+		tc := junitTestCase{
+			Name:      e.Target,
+			Classname: "ron",
+			Time:      seconds(e.Duration),
+			SystemOut: e.Stdout,
+			SystemErr: e.Stderr,
+		}
+		if e.Host != "" {
+			tc.Classname = e.Host
+		}
+		switch {
+		case e.Type == EventSkipped:
+			suite.Skipped++
+			tc.Skipped = &junitMessage{Message: "a before target failed"}
+		case e.Status != 0 || e.Error != "":
+			suite.Failures++
+			tc.Failure = &junitMessage{
+				Message: e.Error,
+				Type:    fmt.Sprintf("exit status %d", e.Status),
+				Body:    e.Stderr,
+			}
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Time = seconds(last.Sub(first))
+	if !first.IsZero() {
+		suite.Timestamp = first.Format(time.RFC3339)
+	}
+	suites := junitTestSuites{
+		Name:     suite.Name,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteAnnotations writes a GitHub Actions error annotation for each
+// failed target with the end of its stderr.
+func WriteAnnotations(w io.Writer, events []*Event) error {
+	for _, e := range events {
+		if e.Type != EventFinished || (e.Status == 0 && e.Error == "") {
+			continue
+		}
+		title := e.Target
+		if e.Host != "" {
+			title += " on " + e.Host
+		}
+		msg := fmt.Sprintf("%s failed after %s: %s", title, roundDuration(e.Duration), e.Error)
+		if tail := lastLines(e.Stderr, annotationLines); tail != "" {
+			msg += "\n" + tail
+		}
+		_, err := fmt.Fprintf(w, "::error title=%s::%s\n", escapeProperty(title), escapeData(msg))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seconds formats d as fractional seconds.
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// lastLines returns the last n lines of s.
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// escapeData escapes an annotation message.
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes an annotation property value.
+func escapeProperty(s string) string {
+	return strings.NewReplacer(":", "%3A", ",", "%2C").Replace(escapeData(s))
+}
@@ -0,0 +1,62 @@
+package target
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func reportEvents() []*Event {
+	start := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	return []*Event{
+		{Time: start, Type: EventStarted, Target: "ron:a"},
+		{Time: start.Add(time.Second), Type: EventFinished, Target: "ron:a", Duration: time.Second, Stdout: "a\n"},
+		{Time: start.Add(2 * time.Second), Type: EventFinished, Target: "ron:b", Host: "example.com", Duration: time.Second, Status: 2, Error: "exit status 2", Stderr: "one\ntwo\n"},
+		{Time: start.Add(2 * time.Second), Type: EventSkipped, Target: "ron:c"},
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	out := &bytes.Buffer{}
+	ok(t, WriteJUnit(out, reportEvents()))
+
+	var suites junitTestSuites
+	ok(t, xml.Unmarshal(out.Bytes(), &suites))
+	equals(t, 3, suites.Tests)
+	equals(t, 1, suites.Failures)
+	equals(t, 1, suites.Skipped)
+	equals(t, "2.000", suites.Time)
+	suite := suites.Suites[0]
+	equals(t, "2020-01-02T03:04:05Z", suite.Timestamp)
+	equals(t, 3, len(suite.Cases))
+
+	a, b, c := suite.Cases[0], suite.Cases[1], suite.Cases[2]
+	equals(t, "ron:a", a.Name)
+	equals(t, "ron", a.Classname)
+	equals(t, "1.000", a.Time)
+	equals(t, "a\n", a.SystemOut)
+	if a.Failure != nil || a.Skipped != nil {
+		t.Errorf("expected a to pass got %+v", a)
+	}
+	equals(t, "example.com", b.Classname)
+	equals(t, "exit status 2", b.Failure.Message)
+	equals(t, "one\ntwo\n", b.Failure.Body)
+	if c.Skipped == nil {
+		t.Errorf("expected c to be skipped got %+v", c)
+	}
+}
+
+func TestWriteAnnotations(t *testing.T) {
+	out := &bytes.Buffer{}
+	ok(t, WriteAnnotations(out, reportEvents()))
+	want := "::error title=ron%3Ab on example.com::ron:b on example.com failed after 1s: exit status 2%0Aone%0Atwo\n"
+	equals(t, want, out.String())
+}
+
+func TestLastLines(t *testing.T) {
+	equals(t, "c\nd", lastLines("a\nb\nc\nd\n", 2))
+	equals(t, "a", lastLines("a", 2))
+	equals(t, "", strings.TrimSpace(lastLines("", 2)))
+}
@@ -0,0 +1,166 @@
+package target
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/upsight/ron/color"
+	"github.com/upsight/ron/execute"
+)
+
+// servicesKey is the context key of the background targets of a run.
+type servicesKey struct{}
+
+// services are the background targets started during a run, they are
+// stopped once the top level target or Make run finishes.
+type services struct {
+	mu      sync.Mutex
+	running []*service
+}
+
+// service is a started background target.
+type service struct {
+	target *Target
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// withServices returns a context which background targets are started
+// in along with a func stopping them. If ctx already has one the func does
+// nothing, leaving the services to whoever created it.
+func withServices(ctx context.Context) (context.Context, func() error) {
+	if _, ok := ctx.Value(servicesKey{}).(*services); ok {
+		return ctx, func() error { return nil }
+	}
+	s := &services{}
+	return context.WithValue(ctx, servicesKey{}, s), s.stop
+}
+
+// start adds a service unless the target has already been started.
+func (s *services) start(t *Target, start func() (*service, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, svc := range s.running {
+		if svc.target == t {
+			return nil
+		}
+	}
+	svc, err := start()
+	if err != nil {
+		return err
+	}
+	s.running = append(s.running, svc)
+	return nil
+}
+
+// stop terminates the services in the reverse order they were started,
+// running the teardown target of each after it exits.
+func (s *services) stop() error {
+	s.mu.Lock()
+	running := s.running
+	s.running = nil
+	s.mu.Unlock()
+
+	var err error
+	for i := len(running) - 1; i >= 0; i-- {
+		svc := running[i]
+		svc.cancel()
+		<-svc.done
+		if terr := svc.target.teardown(); terr != nil && err == nil {
+			err = terr
+		}
+	}
+	return err
+}
+
+// teardown runs the teardown target if one is set. It runs even when the
+// run was interrupted.
+func (t *Target) teardown() error {
+	if t.Teardown == "" {
+		return nil
+	}
+	td, ok := t.targetConfigs.Target(t.Teardown)
+	if !ok {
+		return fmt.Errorf("%s teardown target %s not found", t.Name, t.Teardown)
+	}
+	status, out, err := td.RunContext(context.Background())
+	if status != 0 || err != nil {
+		return fmt.Errorf("%s teardown %d %s %v", t.Name, status, out, err)
+	}
+	return nil
+}
+
+// startService starts the target cmd without waiting for it to finish.
+// Its output is prefixed with the target name, it has no stdin so the
+// terminal is left to foreground targets and it is stopped with its
+// process group when the run finishes.
+func (t *Target) startService(ctx context.Context) (int, error) {
+	s, ok := ctx.Value(servicesKey{}).(*services)
+	if !ok {
+		return 1, fmt.Errorf("%s background target started outside of a run", t.Name)
+	}
+	err := s.start(t, func() (*service, error) {
+		envs, err := t.File.Env.Config()
+		if err != nil {
+			return nil, err
+		}
+		if t.WaitFor != nil {
+			if err := t.WaitFor.Wait(ctx, envs); err != nil {
+				return nil, err
+			}
+		}
+		output := ""
+		if t.targetConfigs != nil {
+			output = t.targetConfigs.Output
+		}
+		prefixOut := execute.NewMux(t.W, output).Writer(t.Name)
+		prefixErr := execute.NewMux(t.WErr, output).Writer(t.Name)
+		stdOut, stdErr, outTail, errTail := t.tailOutput(prefixOut, prefixErr)
+		cmd, err := t.executor(envs).Cmd(t.Cmd, stdOut, stdErr, envs)
+		if err != nil {
+			return nil, err
+		}
+		execute.NoStdin(cmd)
+		start := time.Now()
+		t.record(&Event{Time: start, Type: EventStarted})
+		if err := cmd.Start(); err != nil {
+			execute.Cleanup(cmd)
+			t.record(&Event{Type: EventFinished, Status: 1, Error: err.Error()})
+			return nil, err
+		}
+
+		svcCtx, cancel := context.WithCancel(ctx)
+		svc := &service{target: t, cancel: cancel, done: make(chan struct{})}
+		go func() {
+			defer close(svc.done)
+			defer execute.Cleanup(cmd)
+			err := execute.WaitContext(svcCtx, cmd)
+			prefixOut.Close()
+			prefixErr.Close()
+			status := 0
+			switch {
+			case svcCtx.Err() != nil:
+				// stopped once the run finished.
+				err = nil
+			case err != nil:
+				status = execute.GetExitStatus(err)
+				fmt.Fprintln(t.WErr, color.Red(fmt.Sprintf("background target %s exited: %v", t.Name, err)))
+			}
+			t.record(&Event{
+				Type:     EventFinished,
+				Duration: time.Since(start),
+				Status:   status,
+				Error:    errString(err),
+				Stdout:   outTail.String(),
+				Stderr:   errTail.String(),
+			})
+		}()
+		return svc, nil
+	})
+	if err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
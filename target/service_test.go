@@ -0,0 +1,81 @@
+package target
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stripColor removes terminal color codes.
+func stripColor(s string) string {
+	return regexp.MustCompile("\x1b\\[[0-9;]*m").ReplaceAllString(s, "")
+}
+
+// addTestTarget adds a target to the ron test config file writing to the
+// configs output.
+func addTestTarget(tc *Configs, target *Target) *Target {
+	f := tc.Files[1]
+	target.File = f
+	target.targetConfigs = tc
+	target.W = tc.StdOut
+	target.WErr = tc.StdErr
+	f.Targets[target.Name] = target
+	return target
+}
+
+func TestTargetBackground(t *testing.T) {
+	tc, _, _ := createTestConfigs(t, nil, nil)
+	stdOut := &syncBuffer{}
+	tc.StdOut, tc.StdErr = stdOut, &syncBuffer{}
+	addTestTarget(tc, &Target{Name: "db", Background: true, Teardown: "dbclean", Cmd: "echo db started; trap 'echo db stopped; exit 0' TERM; while true; do sleep 0.01; done"})
+	addTestTarget(tc, &Target{Name: "dbclean", Cmd: "echo dbclean"})
+	test := addTestTarget(tc, &Target{Name: "test", Before: []string{"db", "db"}, Cmd: "sleep 0.2; echo test"})
+
+	start := time.Now()
+	status, _, err := test.Run()
+	ok(t, err)
+	equals(t, 0, status)
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("background target was not stopped, took %s", time.Since(start))
+	}
+	out := stripColor(stdOut.String())
+	for _, want := range []string{"db] db started\n", "test\n", "db] db stopped\n", "dbclean\n"} {
+		if strings.Count(out, want) != 1 {
+			t.Errorf("expected %q once in %q", want, out)
+		}
+	}
+	if strings.Index(out, "test\n") > strings.Index(out, "db stopped") || strings.Index(out, "db stopped") > strings.Index(out, "dbclean") {
+		t.Errorf("expected test, db stopped then dbclean got %q", out)
+	}
+}
+
+func TestMakeBackgroundStoppedOnFailure(t *testing.T) {
+	tc, _, _ := createTestConfigs(t, nil, nil)
+	stdOut := &syncBuffer{}
+	tc.StdOut, tc.StdErr = stdOut, &syncBuffer{}
+	addTestTarget(tc, &Target{Name: "db", Background: true, Cmd: "sleep 30"})
+	addTestTarget(tc, &Target{Name: "fail", Before: []string{"db"}, Cmd: "exit 2"})
+	addTestTarget(tc, &Target{Name: "dbcheck", Before: []string{"db"}, Cmd: "echo still running"})
+	m, err := NewMake(tc)
+	ok(t, err)
+
+	start := time.Now()
+	if err := m.RunContext(context.Background(), "ron:dbcheck", "ron:fail"); err == nil {
+		t.Fatal("expected fail error")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("background target was not stopped, took %s", time.Since(start))
+	}
+	equals(t, "still running\n", stdOut.String())
+}
+
+func TestTargetBackgroundTeardownNotFound(t *testing.T) {
+	tc, _, _ := createTestConfigs(t, nil, nil)
+	db := addTestTarget(tc, &Target{Name: "db", Background: true, Teardown: "nothere", Cmd: "sleep 30"})
+	status, _, err := db.Run()
+	if status == 0 || err == nil || !strings.Contains(err.Error(), "teardown target nothere not found") {
+		t.Fatalf("expected teardown error got %d %v", status, err)
+	}
+}
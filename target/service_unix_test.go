@@ -0,0 +1,57 @@
+//go:build !windows
+// +build !windows
+
+package target
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// processGone reports whether pid has exited, zombies included as they
+// may not be reaped when the test runs as pid 1.
+func processGone(pid int) bool {
+	stat, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return syscall.Kill(pid, 0) != nil
+	}
+	fields := strings.Fields(string(stat))
+	return len(fields) > 2 && fields[2] == "Z"
+}
+
+func TestTargetBackgroundGrandchildren(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ronservice")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+	pidFile := filepath.Join(dir, "pid")
+
+	tc, _, _ := createTestConfigs(t, nil, nil)
+	stdOut := &syncBuffer{}
+	tc.StdOut, tc.StdErr = stdOut, &syncBuffer{}
+	// a server started by a wrapper, like node by npm start. Background
+	// jobs in a non interactive shell ignore SIGINT but not SIGTERM.
+	addTestTarget(tc, &Target{Name: "server", Background: true, Cmd: "cat; echo stdin closed; sleep 300 & echo $! > " + pidFile + "; wait"})
+	test := addTestTarget(tc, &Target{Name: "test", Before: []string{"server"}, Cmd: "while [ ! -s " + pidFile + " ]; do sleep 0.01; done"})
+
+	status, _, err := test.Run()
+	ok(t, err)
+	equals(t, 0, status)
+	if !strings.Contains(stdOut.String(), "stdin closed") {
+		t.Errorf("expected the background target to have no stdin got %q", stdOut.String())
+	}
+	b, err := ioutil.ReadFile(pidFile)
+	ok(t, err)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	ok(t, err)
+	time.Sleep(50 * time.Millisecond)
+	if !processGone(pid) {
+		syscall.Kill(pid, syscall.SIGKILL)
+		t.Fatalf("expected the background target's child %d to be stopped", pid)
+	}
+}
@@ -1,12 +1,13 @@
 package target
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"os"
-	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/upsight/ron/color"
 	"github.com/upsight/ron/execute"
@@ -24,16 +25,46 @@ type Target struct {
 	Description   string    `json:"description" yaml:"description"`
 	W             io.Writer `json:"-" yaml:"-"` // underlying stdout writer
 	WErr          io.Writer `json:"-" yaml:"-"` // underlying stderr writer
+	// Shell runs the cmd with the given interpreter, defaulting to the
+	// files shell and then bash.
+	Shell execute.Shell `json:"shell,omitempty" yaml:"shell,omitempty"`
+	// Image runs the cmd inside a container with the local container
+	// runtime, mounting Volumes and using Workdir when set. These are
+	// ignored on remote hosts.
+	Image   string   `json:"image,omitempty" yaml:"image,omitempty"`
+	Volumes []string `json:"volumes,omitempty" yaml:"volumes,omitempty"`
+	Workdir string   `json:"workdir,omitempty" yaml:"workdir,omitempty"`
+	// Upload and Download are only used with remote hosts, files are
+	// uploaded before cmd is run and downloaded after.
+	Upload   []*execute.Transfer `json:"upload,omitempty" yaml:"upload,omitempty"`
+	Download []*execute.Transfer `json:"download,omitempty" yaml:"download,omitempty"`
+	// Pty requests a pseudo terminal on remote hosts, by default one is
+	// only used when running interactively.
+	Pty *bool `json:"pty,omitempty" yaml:"pty,omitempty"`
+	// Sudo and SudoUser run the cmd on remote hosts through sudo, these
+	// take precedence over the same settings on the remote host.
+	Sudo     bool   `json:"sudo,omitempty" yaml:"sudo,omitempty"`
+	SudoUser string `json:"sudo_user,omitempty" yaml:"sudo_user,omitempty"`
+	// Watch is a list of globs relative to the working directory which
+	// re-run the target when a matching file changes in watch mode.
+	Watch []string `json:"watch,omitempty" yaml:"watch,omitempty"`
+	// WaitFor probes must all pass before the cmd is run locally.
+	WaitFor *WaitFor `json:"wait_for,omitempty" yaml:"wait_for,omitempty"`
+	// Background starts the cmd without waiting for it, keeping it running
+	// until the top level run finishes when its process group is sent
+	// SIGTERM and the Teardown target is run.
+	Background bool   `json:"background,omitempty" yaml:"background,omitempty"`
+	Teardown   string `json:"teardown,omitempty" yaml:"teardown,omitempty"`
 }
 
 // runTargetList executes a list of targets.
-func (t *Target) runTargetList(targets []string) (int, string, error) {
+func (t *Target) runTargetList(ctx context.Context, targets []string) (int, string, error) {
 	for _, target := range targets {
 		if target == t.Name {
 			continue
 		}
 		if t, ok := t.targetConfigs.Target(target); ok {
-			status, out, err := t.Run()
+			status, out, err := t.RunContext(ctx)
 			if status != 0 || err != nil {
 				return status, out, err
 			}
@@ -45,36 +76,44 @@ func (t *Target) runTargetList(targets []string) (int, string, error) {
 // Run executes the targets before commands then runs its own
 // followed by after targets. Try not to make circular references please.
 func (t *Target) Run() (int, string, error) {
+	return t.RunContext(context.Background())
+}
+
+// RunContext is Run which stops running targets and terminates the
+// current cmd when ctx is done. Background targets started by this run
+// are stopped before it returns.
+func (t *Target) RunContext(ctx context.Context) (int, string, error) {
+	ctx, stopServices := withServices(ctx)
+	status, out, err := t.run(ctx)
+	if serr := stopServices(); serr != nil && status == 0 && err == nil {
+		return 1, "", serr
+	}
+	return status, out, err
+}
+
+// run executes the before targets, the cmd and the after targets.
+func (t *Target) run(ctx context.Context) (int, string, error) {
+	if err := ctx.Err(); err != nil {
+		return 1, "", err
+	}
 	if len(t.Before) > 0 {
-		status, out, err := t.runTargetList(t.Before)
+		status, out, err := t.runTargetList(ctx, t.Before)
 		if status != 0 || err != nil {
+			t.record(&Event{Type: EventSkipped, Status: status, Error: errString(err)})
 			return status, out, err
 		}
 	}
 
-	envs, err := t.File.Env.Config()
-	if err != nil {
-		return 1, "", err
-	}
-	cmd, err := execute.CommandNoWait(t.Cmd, t.W, t.WErr, envs)
-	if err != nil {
-		return 1, "", err
+	runCmd := t.runCmd
+	if t.Background {
+		runCmd = t.startService
 	}
-	interrupt := make(chan os.Signal, 1)
-	go func(c *exec.Cmd) {
-		execute.WaitNoop(interrupt, cmd)
-		if c != nil && c.Process != nil {
-			c.Process.Kill()
-		}
-	}(cmd)
-	err = cmd.Wait()
-	if err != nil {
-		status := execute.GetExitStatus(err)
+	if status, err := runCmd(ctx); status != 0 || err != nil {
 		return status, "", err
 	}
 
 	if len(t.After) > 0 {
-		status, out, err := t.runTargetList(t.After)
+		status, out, err := t.runTargetList(ctx, t.After)
 		if status != 0 || err != nil {
 			return status, out, err
 		}
@@ -82,16 +121,222 @@ func (t *Target) Run() (int, string, error) {
 	return 0, "", nil
 }
 
+// runCmd runs the target cmd locally, recording when it starts and
+// finishes.
+func (t *Target) runCmd(ctx context.Context) (int, error) {
+	start := time.Now()
+	t.record(&Event{Time: start, Type: EventStarted})
+	stdOut, stdErr, outTail, errTail := t.tailOutput(t.W, t.WErr)
+	status, err := func() (int, error) {
+		envs, err := t.File.Env.Config()
+		if err != nil {
+			return 1, err
+		}
+		if t.WaitFor != nil {
+			if err := t.WaitFor.Wait(ctx, envs); err != nil {
+				return 1, err
+			}
+		}
+		cmd, err := t.executor(envs).Cmd(t.Cmd, stdOut, stdErr, envs)
+		if err != nil {
+			return 1, err
+		}
+		defer execute.Cleanup(cmd)
+		if err := cmd.Start(); err != nil {
+			return 1, err
+		}
+		if err := execute.WaitContext(ctx, cmd); err != nil {
+			return execute.GetExitStatus(err), err
+		}
+		return 0, nil
+	}()
+	t.record(&Event{
+		Type:     EventFinished,
+		Duration: time.Since(start),
+		Status:   status,
+		Error:    errString(err),
+		Stdout:   outTail.String(),
+		Stderr:   errTail.String(),
+	})
+	return status, err
+}
+
+// record sends the event for this target to the configs recorders.
+func (t *Target) record(e *Event) {
+	if t.targetConfigs == nil || len(t.targetConfigs.Recorders) == 0 {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Target = t.Name
+	if t.File != nil {
+		e.Target = t.File.Basename() + ":" + t.Name
+	}
+	for _, r := range t.targetConfigs.Recorders {
+		r.Record(e)
+	}
+}
+
+// tailOutput returns writers which also keep the end of stdout and stderr
+// for recording. Terminals are left as is so commands can still detect them,
+// which means output written to a terminal isn't recorded.
+func (t *Target) tailOutput(stdOut, stdErr io.Writer) (io.Writer, io.Writer, *tailWriter, *tailWriter) {
+	outTail, errTail := newTailWriter(tailSize), newTailWriter(tailSize)
+	if t.targetConfigs == nil || len(t.targetConfigs.Recorders) == 0 {
+		return stdOut, stdErr, outTail, errTail
+	}
+	if !execute.IsTerminal(stdOut) {
+		stdOut = io.MultiWriter(stdOut, outTail)
+	}
+	if !execute.IsTerminal(stdErr) {
+		stdErr = io.MultiWriter(stdErr, errTail)
+	}
+	return stdOut, stdErr, outTail, errTail
+}
+
+// errString returns the error message or empty for a nil error.
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// executor returns how the target cmd is run locally, expanding any
+// container settings with envs. Only the envs declared in the config are
+// passed into a container.
+func (t *Target) executor(envs MSS) execute.Executor {
+	shell := t.Shell
+	if len(shell) == 0 && t.File != nil {
+		shell = t.File.Shell
+	}
+	if t.Image == "" {
+		return execute.Local{Shell: shell}
+	}
+	getEnv := func(k string) string {
+		return envs[k]
+	}
+	c := &execute.Container{
+		Image:   os.Expand(t.Image, getEnv),
+		Workdir: os.Expand(t.Workdir, getEnv),
+		Shell:   shell,
+	}
+	for _, v := range t.Volumes {
+		c.Volumes = append(c.Volumes, os.Expand(v, getEnv))
+	}
+	if t.File != nil && t.File.Env != nil {
+		c.Envs = t.File.Env.Keys()
+	}
+	return c
+}
+
 // RunRemote executes the target on a remote host. It ignores any
-// before and after targets.
+// before and after targets. Any upload files are copied to the host
+// before the cmd is run and download files copied back afterwards, all
+// over the same connection.
 func (t *Target) RunRemote(conf *execute.SSHConfig) (int, string, error) {
-	s, err := execute.NewSSH(conf, os.Stdin, t.W, t.WErr)
+	return t.RunRemoteContext(context.Background(), conf)
+}
+
+// RunRemoteContext is RunRemote which stops between steps and closes the
+// command session when ctx is done. Output lines are prefixed with the host.
+func (t *Target) RunRemoteContext(ctx context.Context, conf *execute.SSHConfig) (int, string, error) {
+	output := ""
+	if t.targetConfigs != nil {
+		output = t.targetConfigs.Output
+	}
+	stdOut := execute.NewMux(t.W, output).Writer(conf.Host)
+	defer stdOut.Close()
+	stdErr := execute.NewMux(t.WErr, output).Writer(conf.Host)
+	defer stdErr.Close()
+	return t.runRemote(ctx, conf, stdOut, stdErr)
+}
+
+// runRemote executes the target on a remote host writing its output to
+// the given writers.
+func (t *Target) runRemote(ctx context.Context, conf *execute.SSHConfig, stdOut io.Writer, stdErr io.Writer) (int, string, error) {
+	start := time.Now()
+	t.record(&Event{Time: start, Type: EventStarted, Host: conf.Host})
+	stdOut, stdErr, outTail, errTail := t.tailOutput(stdOut, stdErr)
+	status, out, err := t.runRemoteCmd(ctx, conf, stdOut, stdErr)
+	t.record(&Event{
+		Type:     EventFinished,
+		Host:     conf.Host,
+		Duration: time.Since(start),
+		Status:   status,
+		Error:    errString(err),
+		Stdout:   outTail.String(),
+		Stderr:   errTail.String(),
+	})
+	return status, out, err
+}
+
+// runRemoteCmd connects to the remote host, transfers files and runs
+// the cmd.
+func (t *Target) runRemoteCmd(ctx context.Context, conf *execute.SSHConfig, stdOut io.Writer, stdErr io.Writer) (int, string, error) {
+	s, err := execute.NewSSH(conf, os.Stdin, stdOut, stdErr)
 	if err != nil {
 		return 1, "", err
 	}
+	s.Pty = t.pty(conf)
+	if err := s.ConnectContext(ctx); err != nil {
+		return 1, "", err
+	}
+	defer s.Close()
+
+	for _, u := range t.Upload {
+		if err := ctx.Err(); err != nil {
+			return 1, "", err
+		}
+		if err := s.Upload(u.Src, u.Dst); err != nil {
+			return 1, "", err
+		}
+	}
+	cmd := t.Cmd
+	if sudo, user := t.sudo(conf); sudo {
+		if t.targetConfigs != nil {
+			s.SudoPassword = t.targetConfigs.SudoPassword
+		}
+		cmd = execute.SudoCommand(cmd, user, s.SudoPassword != "")
+	}
+	if strings.TrimSpace(t.Cmd) != "" {
+		if err := s.RunCommandContext(ctx, cmd, nil); err != nil {
+			return 1, "", err
+		}
+	}
+	for _, d := range t.Download {
+		if err := ctx.Err(); err != nil {
+			return 1, "", err
+		}
+		if err := s.Download(d.Src, d.Dst); err != nil {
+			return 1, "", err
+		}
+	}
+	return 0, "", nil
+}
 
-	err = s.RunCommand(t.Cmd, nil)
-	return 0, "", err
+// pty returns whether a pseudo terminal should be requested on the remote
+// host. The target setting takes precedence over the remote host, falling
+// back to whether ron is running interactively.
+func (t *Target) pty(conf *execute.SSHConfig) bool {
+	switch {
+	case t.Pty != nil:
+		return *t.Pty
+	case conf.Pty != nil:
+		return *conf.Pty
+	}
+	return execute.IsInteractive()
+}
+
+// sudo returns whether the target should be run with sudo on the remote
+// host and the user to run as, an empty user being root.
+func (t *Target) sudo(conf *execute.SSHConfig) (bool, string) {
+	user := t.SudoUser
+	if user == "" {
+		user = conf.SudoUser
+	}
+	return t.Sudo || conf.Sudo || user != "", user
 }
 
 // List displays the defined before, after, description and cmd of the target.
@@ -134,6 +379,44 @@ func (t *Target) List(verbose bool, nameWidth int) {
 		out += fmt.Sprintln(afterList)
 	}
 
+	// target shell
+	if len(t.Shell) > 0 {
+		out += fmt.Sprintf("  - shell: %s\n", t.Shell)
+	}
+
+	// target container
+	if t.Image != "" {
+		out += fmt.Sprintf("  - image: %s\n", t.Image)
+	}
+
+	// target sudo
+	if t.Sudo || t.SudoUser != "" {
+		out += fmt.Sprintf("  - sudo: %s\n", strings.TrimSpace("true "+t.SudoUser))
+	}
+
+	// target background service
+	if t.Background {
+		out += fmt.Sprintf("  - background: %s\n", strings.TrimSpace("true "+t.Teardown))
+	}
+
+	// target readiness probes
+	if t.WaitFor != nil {
+		out += fmt.Sprintf("  - wait_for: %s\n", t.WaitFor)
+	}
+
+	// target watch globs
+	if len(t.Watch) > 0 {
+		out += fmt.Sprintf("  - watch: %s\n", strings.Join(t.Watch, ", "))
+	}
+
+	// target uploads and downloads
+	for _, u := range t.Upload {
+		out += fmt.Sprintf("  - upload: %s -> %s\n", u.Src, u.Dst)
+	}
+	for _, d := range t.Download {
+		out += fmt.Sprintf("  - download: %s -> %s\n", d.Src, d.Dst)
+	}
+
 	// target command
 	out += fmt.Sprintf("  - cmd:\n    ")
 	out += fmt.Sprintln(strings.Replace(t.Cmd, "\n", "\n    ", -1))
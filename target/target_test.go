@@ -1,14 +1,17 @@
 package target
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 	"reflect"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/upsight/ron/color"
+	"github.com/upsight/ron/execute"
 )
 
 // ok fails the test if an err is not nil.
@@ -98,3 +101,88 @@ func TestTargetListBadWriter(t *testing.T) {
 	target.W = &badWriter{}
 	target.List(true, 0)
 }
+
+func TestTargetSudo(t *testing.T) {
+	tests := []struct {
+		target   *Target
+		host     *execute.SSHConfig
+		wantSudo bool
+		wantUser string
+	}{
+		{&Target{}, &execute.SSHConfig{}, false, ""},
+		{&Target{Sudo: true}, &execute.SSHConfig{}, true, ""},
+		{&Target{}, &execute.SSHConfig{Sudo: true}, true, ""},
+		{&Target{}, &execute.SSHConfig{SudoUser: "deploy"}, true, "deploy"},
+		{&Target{SudoUser: "app"}, &execute.SSHConfig{SudoUser: "deploy"}, true, "app"},
+	}
+	for i, tt := range tests {
+		sudo, user := tt.target.sudo(tt.host)
+		if sudo != tt.wantSudo || user != tt.wantUser {
+			t.Errorf("%d want %v %q got %v %q", i, tt.wantSudo, tt.wantUser, sudo, user)
+		}
+	}
+}
+
+func TestTargetPty(t *testing.T) {
+	prevIsInteractive := execute.IsInteractive
+	defer func() { execute.IsInteractive = prevIsInteractive }()
+	execute.IsInteractive = func() bool { return true }
+
+	on, off := true, false
+	tests := []struct {
+		target *Target
+		host   *execute.SSHConfig
+		want   bool
+	}{
+		{&Target{}, &execute.SSHConfig{}, true},
+		{&Target{}, &execute.SSHConfig{Pty: &off}, false},
+		{&Target{Pty: &on}, &execute.SSHConfig{Pty: &off}, true},
+		{&Target{Pty: &off}, &execute.SSHConfig{}, false},
+	}
+	for i, tt := range tests {
+		if got := tt.target.pty(tt.host); got != tt.want {
+			t.Errorf("%d want %v got %v", i, tt.want, got)
+		}
+	}
+}
+
+func TestTargetExecutor(t *testing.T) {
+	target := &Target{File: &File{Shell: execute.Shell{"sh"}}}
+	equals(t, execute.Local{Shell: execute.Shell{"sh"}}, target.executor(nil))
+	target.Shell = execute.Shell{"python3"}
+	equals(t, execute.Local{Shell: execute.Shell{"python3"}}, target.executor(nil))
+	target = &Target{Image: "golang:$GO_VERSION", Volumes: []string{"$CACHE:/cache"}, Workdir: "/src"}
+	e, ok := target.executor(MSS{"GO_VERSION": "1.21", "CACHE": "/tmp/cache"}).(*execute.Container)
+	if !ok {
+		t.Fatal("expected container executor")
+	}
+	equals(t, &execute.Container{Image: "golang:1.21", Volumes: []string{"/tmp/cache:/cache"}, Workdir: "/src"}, e)
+
+	target, _, _ = createTestTarget(t, "ron:prep", nil, nil)
+	target.Image = "alpine"
+	e, ok = target.executor(nil).(*execute.Container)
+	if !ok {
+		t.Fatal("expected container executor")
+	}
+	equals(t, target.File.Env.Keys(), e.Envs)
+	for _, k := range e.Envs {
+		if k == "PATH" || k == "GOPATH" {
+			t.Errorf("expected only declared envs got %v", e.Envs)
+		}
+	}
+}
+
+func TestTargetRunContext(t *testing.T) {
+	target, _, _ := createTestTarget(t, "ron:prep", nil, nil)
+	target.Cmd = "sleep 30"
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	status, _, err := target.RunContext(ctx)
+	if status == 0 || err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded got %d %v", status, err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("target was not cancelled, took %s", time.Since(start))
+	}
+}
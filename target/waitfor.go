@@ -0,0 +1,165 @@
+package target
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/upsight/ron/execute"
+)
+
+// Default timeout and interval of wait_for probes.
+var (
+	DefaultWaitTimeout  = 30 * time.Second
+	DefaultWaitInterval = time.Second
+)
+
+// WaitFor is a set of readiness probes which are retried every Interval
+// until they all pass before a target's cmd is run. Probe values are
+// expanded with the targets envs.
+type WaitFor struct {
+	// TCP is a host:port which must accept connections.
+	TCP string `json:"tcp,omitempty" yaml:"tcp,omitempty"`
+	// HTTP is a url which must respond with Status, or any 2xx or 3xx
+	// status when Status isn't set.
+	HTTP   string `json:"http,omitempty" yaml:"http,omitempty"`
+	Status int    `json:"status,omitempty" yaml:"status,omitempty"`
+	// File is a path which must exist.
+	File string `json:"file,omitempty" yaml:"file,omitempty"`
+	// Cmd is a shell command which must exit 0.
+	Cmd      string        `json:"cmd,omitempty" yaml:"cmd,omitempty"`
+	Timeout  time.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	Interval time.Duration `json:"interval,omitempty" yaml:"interval,omitempty"`
+}
+
+// probe is a single readiness check.
+type probe struct {
+	kind  string
+	value string
+	check func(ctx context.Context) error
+}
+
+// Wait blocks until every probe passes, returning an error with the last
+// failure once Timeout has passed or ctx is done.
+func (w *WaitFor) Wait(ctx context.Context, envs MSS) error {
+	timeout, interval := w.Timeout, w.Interval
+	if timeout <= 0 {
+		timeout = DefaultWaitTimeout
+	}
+	if interval <= 0 {
+		interval = DefaultWaitInterval
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for _, p := range w.probes(envs) {
+		var last error
+		for {
+			err := p.check(waitCtx)
+			if err == nil {
+				break
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if waitCtx.Err() != nil {
+				// a check cut short by the timeout has a less useful error.
+				if last == nil {
+					last = err
+				}
+				return fmt.Errorf("wait_for %s %s: timed out after %s: %v", p.kind, p.value, timeout, last)
+			}
+			last = err
+			select {
+			case <-waitCtx.Done():
+			case <-time.After(interval):
+			}
+		}
+	}
+	return nil
+}
+
+// probes returns the configured probes with their values expanded.
+func (w *WaitFor) probes(envs MSS) []probe {
+	expand := func(s string) string {
+		return os.Expand(s, func(k string) string {
+			return envs[k]
+		})
+	}
+	probes := []probe{}
+	if w.TCP != "" {
+		addr := expand(w.TCP)
+		probes = append(probes, probe{"tcp", addr, func(ctx context.Context) error {
+			conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		}})
+	}
+	if w.HTTP != "" {
+		url := expand(w.HTTP)
+		probes = append(probes, probe{"http", url, func(ctx context.Context) error {
+			return checkHTTP(ctx, url, w.Status)
+		}})
+	}
+	if w.File != "" {
+		path := expand(w.File)
+		probes = append(probes, probe{"file", path, func(ctx context.Context) error {
+			_, err := os.Stat(path)
+			return err
+		}})
+	}
+	if w.Cmd != "" {
+		cmd := w.Cmd
+		probes = append(probes, probe{"cmd", strings.TrimSpace(cmd), func(ctx context.Context) error {
+			out := &bytes.Buffer{}
+			if _, err := execute.CommandContext(ctx, cmd, out, out, envs); err != nil {
+				if msg := strings.TrimSpace(out.String()); msg != "" {
+					return fmt.Errorf("%v: %s", err, lastLines(msg, 1))
+				}
+				return err
+			}
+			return nil
+		}})
+	}
+	return probes
+}
+
+// checkHTTP requests url and checks the response status.
+func checkHTTP(ctx context.Context, url string, status int) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	switch {
+	case status != 0 && resp.StatusCode != status:
+		return fmt.Errorf("expected status %d got %d", status, resp.StatusCode)
+	case status == 0 && (resp.StatusCode < 200 || resp.StatusCode >= 400):
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// String describes the probes for listing.
+func (w *WaitFor) String() string {
+	parts := []string{}
+	for _, p := range []struct{ kind, value string }{
+		{"tcp", w.TCP}, {"http", w.HTTP}, {"file", w.File}, {"cmd", strings.TrimSpace(w.Cmd)},
+	} {
+		if p.value != "" {
+			parts = append(parts, p.kind+" "+p.value)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,105 @@
+package target
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestWaitForUnmarshal(t *testing.T) {
+	var w WaitFor
+	ok(t, yaml.Unmarshal([]byte("tcp: localhost:5432\nhttp: http://localhost/health\nstatus: 204\ntimeout: 1m\ninterval: 500ms\n"), &w))
+	equals(t, "localhost:5432", w.TCP)
+	equals(t, 204, w.Status)
+	equals(t, time.Minute, w.Timeout)
+	equals(t, 500*time.Millisecond, w.Interval)
+	equals(t, "tcp localhost:5432, http http://localhost/health", w.String())
+}
+
+func TestWaitForTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	ok(t, err)
+	addr := l.Addr().String()
+	l.Close()
+
+	// start listening after the first attempt fails.
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return
+		}
+		defer l.Close()
+		conn, err := l.Accept()
+		if err == nil {
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(addr)
+	w := &WaitFor{TCP: "127.0.0.1:$PORT", Interval: 10 * time.Millisecond, Timeout: 5 * time.Second}
+	ok(t, w.Wait(context.Background(), MSS{"PORT": port}))
+}
+
+func TestWaitForHTTP(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	w := &WaitFor{HTTP: ts.URL, Status: http.StatusNoContent, Interval: 10 * time.Millisecond}
+	ok(t, w.Wait(context.Background(), nil))
+	equals(t, 3, calls)
+}
+
+func TestWaitForFileAndCmd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ronwait")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "ready")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		ioutil.WriteFile(file, nil, 0644)
+	}()
+	w := &WaitFor{File: file, Cmd: "test -f $READY", Interval: 10 * time.Millisecond}
+	ok(t, w.Wait(context.Background(), MSS{"READY": file}))
+}
+
+func TestWaitForTimeout(t *testing.T) {
+	w := &WaitFor{Cmd: "echo not yet; exit 1", Interval: 10 * time.Millisecond, Timeout: 100 * time.Millisecond}
+	err := w.Wait(context.Background(), nil)
+	if err == nil || !strings.Contains(err.Error(), "wait_for cmd echo not yet; exit 1: timed out after 100ms: exit status 1: not yet") {
+		t.Fatalf("expected timeout error got %v", err)
+	}
+}
+
+func TestWaitForCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := &WaitFor{File: "nothere"}
+	equals(t, context.Canceled, w.Wait(ctx, nil))
+}
+
+func TestTargetWaitFor(t *testing.T) {
+	target, stdOut, _ := createTestTarget(t, "ron:hello", nil, nil)
+	target.WaitFor = &WaitFor{File: "nothere", Interval: 10 * time.Millisecond, Timeout: 50 * time.Millisecond}
+	status, _, err := target.Run()
+	if status == 0 || err == nil {
+		t.Fatalf("expected wait_for error got %d %v", status, err)
+	}
+	equals(t, "", stdOut.String())
+}
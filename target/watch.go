@@ -0,0 +1,73 @@
+package target
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/upsight/ron/color"
+	"github.com/upsight/ron/watch"
+)
+
+// WatchPatterns returns the watch globs of the named targets and of the
+// targets they run before and after.
+func (m *Make) WatchPatterns(names ...string) []string {
+	patterns := []string{}
+	seen := map[*Target]bool{}
+	var walk func(names []string)
+	walk = func(names []string) {
+		for _, name := range names {
+			t, ok := m.Configs.Target(name)
+			if !ok || seen[t] {
+				continue
+			}
+			seen[t] = true
+			patterns = append(patterns, t.Watch...)
+			walk(t.Before)
+			walk(t.After)
+		}
+	}
+	walk(names)
+	return patterns
+}
+
+// Watch runs the named targets and runs them again each time w reports
+// changed files. A run still in progress is cancelled first, which
+// terminates the process group of its cmd. Watch returns when ctx is done.
+func (m *Make) Watch(ctx context.Context, w *watch.Watcher, names ...string) error {
+	for _, name := range names {
+		if _, ok := m.Configs.Target(name); !ok {
+			return fmt.Errorf("%s target not found", name)
+		}
+	}
+	var cancel context.CancelFunc
+	var done chan struct{}
+	start := func() {
+		runCtx, c := context.WithCancel(ctx)
+		cancel, done = c, make(chan struct{})
+		go func(done chan struct{}) {
+			defer close(done)
+			err := m.RunContext(runCtx, names...)
+			if runCtx.Err() != nil {
+				return
+			}
+			if err != nil {
+				fmt.Fprintln(m.Configs.StdErr, color.Red(err.Error()))
+			}
+			fmt.Fprintln(m.Configs.StdErr, color.Yellow("watching for changes"))
+		}(done)
+	}
+	stop := func() {
+		cancel()
+		<-done
+	}
+
+	start()
+	err := w.Watch(ctx, func(files []string) {
+		fmt.Fprintln(m.Configs.StdErr, color.Yellow(fmt.Sprintf("changed %s, running %s", watch.Describe(files), strings.Join(names, " "))))
+		stop()
+		start()
+	})
+	stop()
+	return err
+}
@@ -0,0 +1,100 @@
+package target
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/upsight/ron/watch"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+func TestMakeWatchPatterns(t *testing.T) {
+	tc, _, _ := createTestConfigs(t, nil, nil)
+	hello, _ := tc.Target("ron:hello")
+	hello.Watch = []string{"*.go"}
+	goodbye, _ := tc.Target("ron:goodbye")
+	goodbye.Watch = []string{"*.yaml"}
+	prep, _ := tc.Target("ron:prep")
+	prep.Watch = []string{"prep/**"}
+	m, err := NewMake(tc)
+	ok(t, err)
+	equals(t, []string{"prep/**", "*.go", "*.yaml"}, m.WatchPatterns("ron:prep"))
+	equals(t, []string{"*.go"}, m.WatchPatterns("ron:hello", "nothere"))
+}
+
+func TestMakeWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ronwatch")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	tc, _, _ := createTestConfigs(t, nil, nil)
+	stdOut, stdErr := &syncBuffer{}, &syncBuffer{}
+	tc.StdErr = stdErr
+	hello, _ := tc.Target("ron:hello")
+	hello.W = stdOut
+	m, err := NewMake(tc)
+	ok(t, err)
+
+	w, err := watch.New(dir)
+	ok(t, err)
+	w.Debounce = 20 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- m.Watch(ctx, w, "ron:hello")
+	}()
+
+	waitFor := func(want string) {
+		deadline := time.Now().Add(5 * time.Second)
+		for strings.Count(stdOut.String(), "hello\n") < strings.Count(want, "hello\n") {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %q got %q %q", want, stdOut.String(), stdErr.String())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor("hello\n")
+	// let the watcher add the directory before changing it.
+	time.Sleep(100 * time.Millisecond)
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte("package a"), 0644))
+	waitFor("hello\nhello\n")
+
+	cancel()
+	equals(t, context.Canceled, <-done)
+	if !strings.Contains(stdErr.String(), "changed a.go, running ron:hello") {
+		t.Errorf("expected changed message got %q", stdErr.String())
+	}
+}
+
+func TestMakeWatchNotFound(t *testing.T) {
+	tc, _, _ := createTestConfigs(t, nil, nil)
+	m, err := NewMake(tc)
+	ok(t, err)
+	if err := m.Watch(context.Background(), &watch.Watcher{}, "nothere"); err == nil {
+		t.Fatal("expected target not found error")
+	}
+}
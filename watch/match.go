@@ -0,0 +1,128 @@
+package watch
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"strings"
+)
+
+// Match reports whether the slash separated path relative to the watched
+// root matches the glob pattern. A "**" segment matches any number of
+// directories and patterns without a slash match the base name at any
+// depth, so "*.go" matches "cmd/ron/main.go".
+func Match(pattern, name string) bool {
+	pattern = strings.TrimPrefix(pattern, "./")
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(strings.TrimPrefix(pattern, "/"), "/"), strings.Split(name, "/"))
+}
+
+// matchSegments matches the pattern segments against the path segments.
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := len(name); i >= 0; i-- {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// ignoreRule is a single .gitignore line.
+type ignoreRule struct {
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+// Ignore is a list of .gitignore style patterns, later patterns taking
+// precedence and "!" negating an earlier match.
+type Ignore struct {
+	rules []ignoreRule
+}
+
+// ReadIgnoreFile reads the patterns of a .gitignore file, a missing file
+// being an empty list.
+func ReadIgnoreFile(filename string) (*Ignore, error) {
+	i := &Ignore{}
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return i, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		i.Add(scanner.Text())
+	}
+	return i, scanner.Err()
+}
+
+// Add adds a .gitignore style pattern, blank lines and comments are
+// skipped.
+func (i *Ignore) Add(line string) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+	r := ignoreRule{}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	// a pattern with a slash other than at the end is relative to the root.
+	if strings.Contains(line, "/") && !strings.HasPrefix(line, "**/") {
+		line = "/" + strings.TrimPrefix(line, "/")
+	}
+	r.pattern = line
+	i.rules = append(i.rules, r)
+}
+
+// Ignored reports whether the slash separated path relative to the root
+// is ignored. Paths inside an ignored directory are also ignored.
+func (i *Ignore) Ignored(name string, isDir bool) bool {
+	if i == nil || len(i.rules) == 0 {
+		return false
+	}
+	parts := strings.Split(name, "/")
+	for n := 1; n < len(parts); n++ {
+		if i.match(strings.Join(parts[:n], "/"), true) {
+			return true
+		}
+	}
+	return i.match(name, isDir)
+}
+
+// match applies the rules to a single path, the last matching rule wins.
+func (i *Ignore) match(name string, isDir bool) bool {
+	ignored := false
+	for _, r := range i.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if Match(r.pattern, name) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
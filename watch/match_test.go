@@ -0,0 +1,71 @@
+package watch
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/ron/main.go", true},
+		{"*.go", "main.yaml", false},
+		{"cmd/*.go", "cmd/main.go", true},
+		{"cmd/*.go", "cmd/ron/main.go", false},
+		{"./cmd/*.go", "cmd/main.go", true},
+		{"cmd/**/*.go", "cmd/main.go", true},
+		{"cmd/**/*.go", "cmd/ron/sub/main.go", true},
+		{"**/*.go", "a/b/c.go", true},
+		{"**/testdata", "target/testdata", true},
+		{"/vendor", "vendor", true},
+		{"/vendor", "a/vendor", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("Match(%q, %q) want %v got %v", tt.pattern, tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestIgnore(t *testing.T) {
+	i := &Ignore{}
+	for _, line := range []string{
+		"# comment",
+		"",
+		"*.log",
+		"!keep.log",
+		"build/",
+		"/dist",
+		"docs/*.html",
+	} {
+		i.Add(line)
+	}
+	tests := []struct {
+		name  string
+		isDir bool
+		want  bool
+	}{
+		{"app.log", false, true},
+		{"logs/app.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"build/out/app", false, true},
+		{"src/build/app", false, true},
+		{"dist/app.js", false, true},
+		{"src/dist/app.js", false, false},
+		{"docs/index.html", false, true},
+		{"src/docs/index.html", false, false},
+		{"main.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := i.Ignored(tt.name, tt.isDir); got != tt.want {
+			t.Errorf("Ignored(%q, %v) want %v got %v", tt.name, tt.isDir, tt.want, got)
+		}
+	}
+	var none *Ignore
+	if none.Ignored("a", false) {
+		t.Error("expected nil Ignore to ignore nothing")
+	}
+}
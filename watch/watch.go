@@ -0,0 +1,181 @@
+// Package watch reports batches of changed files in a directory tree,
+// filtering them with glob patterns and .gitignore files.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is how long the tree must be quiet after a change before
+// the changed files are reported.
+var DefaultDebounce = 200 * time.Millisecond
+
+// Watcher watches all directories under Root. Changed files are reported
+// as slash separated paths relative to Root when they match one of the
+// Include patterns, or any file if there are none, and don't match an
+// Exclude pattern or Ignore. Excluded and ignored directories are not
+// watched.
+type Watcher struct {
+	Root     string
+	Include  []string
+	Exclude  []string
+	Ignore   *Ignore
+	Debounce time.Duration
+}
+
+// New creates a Watcher for root which ignores the .git directory and the
+// files in root's .gitignore.
+func New(root string) (*Watcher, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	ignore, err := ReadIgnoreFile(filepath.Join(root, ".gitignore"))
+	if err != nil {
+		return nil, err
+	}
+	ignore.Add(".git/")
+	return &Watcher{Root: root, Ignore: ignore, Debounce: DefaultDebounce}, nil
+}
+
+// Watch calls changed with the sorted files that changed once no other
+// change has happened for the debounce period. changed is called from the
+// watching goroutine, events arriving while it runs are batched for the
+// next call. Watch returns when ctx is done.
+func (w *Watcher) Watch(ctx context.Context, changed func(files []string)) error {
+	fs, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fs.Close()
+	if err := w.addDir(fs, w.Root); err != nil {
+		return err
+	}
+
+	debounce := w.Debounce
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	pending := map[string]struct{}{}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-fs.Errors:
+			return err
+		case ev := <-fs.Events:
+			// editors and tools often touch modes on save, only content
+			// changes are reported.
+			if ev.Op == fsnotify.Chmod {
+				continue
+			}
+			rel, ok := w.rel(ev.Name)
+			if !ok {
+				continue
+			}
+			if ev.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					if !w.skipDir(rel) {
+						w.addDir(fs, ev.Name)
+					}
+					continue
+				}
+			}
+			if !w.match(rel) {
+				continue
+			}
+			pending[rel] = struct{}{}
+			timer.Reset(debounce)
+		case <-timer.C:
+			files := make([]string, 0, len(pending))
+			for f := range pending {
+				files = append(files, f)
+			}
+			sort.Strings(files)
+			pending = map[string]struct{}{}
+			changed(files)
+		}
+	}
+}
+
+// addDir watches dir and every directory below it which isn't skipped.
+func (w *Watcher) addDir(fs *fsnotify.Watcher, dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// directories can disappear while walking.
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if rel, ok := w.rel(path); ok && rel != "." && w.skipDir(rel) {
+			return filepath.SkipDir
+		}
+		return fs.Add(path)
+	})
+}
+
+// rel returns the slash separated path relative to Root.
+func (w *Watcher) rel(path string) (string, bool) {
+	rel, err := filepath.Rel(w.Root, path)
+	if err != nil {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// skipDir reports whether the directory is excluded or ignored.
+func (w *Watcher) skipDir(rel string) bool {
+	if w.Ignore.Ignored(rel, true) {
+		return true
+	}
+	for _, p := range w.Exclude {
+		if Match(p, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether a changed file should be reported.
+func (w *Watcher) match(rel string) bool {
+	if w.Ignore.Ignored(rel, false) {
+		return false
+	}
+	for _, p := range w.Exclude {
+		if Match(p, rel) {
+			return false
+		}
+	}
+	if len(w.Include) == 0 {
+		return true
+	}
+	for _, p := range w.Include {
+		if Match(p, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// Describe shortens a list of changed files for display.
+func Describe(files []string) string {
+	const max = 3
+	if len(files) <= max {
+		return strings.Join(files, " ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(files[:max], " "), len(files)-max)
+}
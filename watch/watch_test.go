@@ -0,0 +1,98 @@
+package watch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ronwatch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, d := range []string{"src", "node_modules", "out"} {
+		if err := os.Mkdir(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, ".gitignore"), []byte("out/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Include = []string{"*.go"}
+	w.Exclude = []string{"node_modules"}
+	w.Debounce = 50 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	batches := make(chan []string, 10)
+	done := make(chan error)
+	go func() {
+		done <- w.Watch(ctx, func(files []string) {
+			batches <- files
+		})
+	}()
+	// give the watcher time to add the directories.
+	time.Sleep(100 * time.Millisecond)
+
+	write := func(name string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("package a\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("src/a.go")
+	write("src/b.go")
+	write("src/a.txt")
+	write("node_modules/c.go")
+	write("out/d.go")
+	os.Chmod(filepath.Join(dir, "src", "a.go"), 0600)
+
+	select {
+	case files := <-batches:
+		want := []string{"src/a.go", "src/b.go"}
+		if !reflect.DeepEqual(files, want) {
+			t.Errorf("want %v got %v", want, files)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for changes")
+	}
+
+	// new directories are watched.
+	if err := os.Mkdir(filepath.Join(dir, "src", "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	write("src/sub/e.go")
+	select {
+	case files := <-batches:
+		want := []string{"src/sub/e.go"}
+		if !reflect.DeepEqual(files, want) {
+			t.Errorf("want %v got %v", want, files)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for changes")
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected context canceled got %v", err)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	if got := Describe([]string{"a", "b"}); got != "a b" {
+		t.Errorf("want a b got %s", got)
+	}
+	if got := Describe([]string{"a", "b", "c", "d", "e"}); got != "a b c and 2 more" {
+		t.Errorf("want a b c and 2 more got %s", got)
+	}
+}